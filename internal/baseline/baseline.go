@@ -0,0 +1,167 @@
+// Package baseline suppresses known broken links so that a crawl only fails on
+// new errors. Errors can be ignored explicitly through an ignore file or
+// accepted wholesale by snapshotting a report into a baseline file.
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"linkcheck/internal/crawler"
+)
+
+// Version identifies the layout of the baseline file.
+const Version = 1
+
+// Baseline is a snapshot of the errors of a previous crawl.
+type Baseline struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// Entry identifies one known error. Errors match an entry when source, target
+// and type agree; status and message are informational.
+type Entry struct {
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Type    string `json:"type"`
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type entryKey struct {
+	source, target, kind string
+}
+
+func (e Entry) key() entryKey {
+	return entryKey{source: e.Source, target: e.Target, kind: e.Type}
+}
+
+func newEntry(err crawler.Error) Entry {
+	return Entry{Source: err.Source, Target: err.Target, Type: err.Type, Status: err.Status, Message: err.Message}
+}
+
+// New snapshots the errors of r, deduplicated and sorted.
+func New(r *crawler.Report, now time.Time) *Baseline {
+	b := &Baseline{Version: Version, CreatedAt: now.UTC(), Entries: []Entry{}}
+	if r == nil {
+		return b
+	}
+	seen := make(map[entryKey]struct{}, len(r.Errors))
+	for _, err := range r.Errors {
+		entry := newEntry(err)
+		if _, ok := seen[entry.key()]; ok {
+			continue
+		}
+		seen[entry.key()] = struct{}{}
+		b.Entries = append(b.Entries, entry)
+	}
+	sort.Slice(b.Entries, func(i, j int) bool {
+		a, c := b.Entries[i], b.Entries[j]
+		if a.Source != c.Source {
+			return a.Source < c.Source
+		}
+		if a.Target != c.Target {
+			return a.Target < c.Target
+		}
+		return a.Type < c.Type
+	})
+	return b
+}
+
+// Load reads a baseline file. A missing file yields a nil baseline so callers
+// can bootstrap one on the first run.
+func Load(path string) (*Baseline, error) {
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(payload, &b); err != nil {
+		return nil, fmt.Errorf("parse baseline: %w", err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline as indented JSON, creating parent directories.
+func (b *Baseline) Save(path string) error {
+	payload, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(payload, '\n'), 0o644)
+}
+
+// Apply moves errors matched by an active ignore rule into r.Ignored and
+// errors recorded in the baseline into r.Baselined, leaving only new errors in
+// r.Errors. Baseline entries that no longer occur are listed in
+// r.BaselineFixed, and r.BaselineApplied is set when base is not nil. Either
+// ignore or base may be nil. Apply returns the ignore
+// rules that have expired so callers can warn about them.
+func Apply(r *crawler.Report, ignore *IgnoreList, base *Baseline, now time.Time) []IgnoreRule {
+	if r == nil {
+		return nil
+	}
+	known := make(map[entryKey]bool)
+	if base != nil {
+		for _, entry := range base.Entries {
+			known[entry.key()] = false
+		}
+	}
+
+	remaining := r.Errors[:0:0]
+	for _, err := range r.Errors {
+		// An ignored error still occurs, so its baseline entry is not
+		// fixed.
+		key := newEntry(err).key()
+		_, baselined := known[key]
+		if baselined {
+			known[key] = true
+		}
+		if rule, ok := ignore.match(err, now); ok {
+			r.Ignored = append(r.Ignored, crawler.IgnoredError{Error: err, Rule: rule.Pattern(), Reason: rule.Reason, Baselined: baselined})
+			continue
+		}
+		if baselined {
+			r.Baselined = append(r.Baselined, err)
+			continue
+		}
+		remaining = append(remaining, err)
+	}
+	r.Errors = remaining
+
+	if base != nil {
+		r.BaselineApplied = true
+		for _, entry := range base.Entries {
+			if known[entry.key()] {
+				continue
+			}
+			r.BaselineFixed = append(r.BaselineFixed, crawler.Error{
+				Source:  entry.Source,
+				Target:  entry.Target,
+				Type:    entry.Type,
+				Status:  entry.Status,
+				Message: entry.Message,
+			})
+		}
+	}
+	return ignore.Expired(now)
+}
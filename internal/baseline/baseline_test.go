@@ -0,0 +1,138 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"linkcheck/internal/crawler"
+)
+
+func TestLoadIgnoreListAndApply(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ignore.yaml")
+	content := `ignore:
+  - url: "https://legacy.test/*"
+    reason: legacy section
+  - regex: '^https://example\.test/old/.*\.pdf$'
+    source: "https://example.test/archive/*"
+  - url: "https://expired.test/*"
+    expires: 2024-01-01
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write ignore file: %v", err)
+	}
+	list, err := LoadIgnoreList(path)
+	if err != nil {
+		t.Fatalf("load ignore list: %v", err)
+	}
+
+	report := &crawler.Report{Errors: []crawler.Error{
+		{Source: "https://example.test/", Target: "https://legacy.test/a/b", Type: "http", Status: 404},
+		{Source: "https://example.test/archive/2019", Target: "https://example.test/old/file.pdf", Type: "http", Status: 404},
+		{Source: "https://example.test/news", Target: "https://example.test/old/file.pdf", Type: "http", Status: 404},
+		{Source: "https://example.test/", Target: "https://expired.test/x", Type: "request"},
+	}}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	expired := Apply(report, list, nil, now)
+
+	if len(report.Ignored) != 2 {
+		t.Fatalf("expected two ignored errors, got %+v", report.Ignored)
+	}
+	if report.Ignored[0].Reason != "legacy section" || report.Ignored[0].Rule != "https://legacy.test/*" {
+		t.Fatalf("unexpected ignored error: %+v", report.Ignored[0])
+	}
+	if len(report.Errors) != 2 {
+		t.Fatalf("expected out-of-scope and expired errors to remain, got %+v", report.Errors)
+	}
+	if len(expired) != 1 || expired[0].URL != "https://expired.test/*" {
+		t.Fatalf("expected expired rule to be reported, got %+v", expired)
+	}
+}
+
+func TestLoadIgnoreListRejectsInvalidRules(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"missing-pattern.yaml": "ignore:\n  - reason: nothing\n",
+		"bad-regex.yaml":       "ignore:\n  - regex: '('\n",
+		"bad-expiry.yaml":      "ignore:\n  - url: x\n    expires: tomorrow\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if _, err := LoadIgnoreList(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBaselineSuppressesKnownErrorsAndFlagsFixed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	first := &crawler.Report{Errors: []crawler.Error{
+		{Source: "https://example.test/", Target: "https://example.test/a", Type: "http", Status: 404},
+		{Source: "https://example.test/", Target: "https://example.test/b", Type: "http", Status: 404},
+	}}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := New(first, now).Save(path); err != nil {
+		t.Fatalf("save baseline: %v", err)
+	}
+	base, err := Load(path)
+	if err != nil {
+		t.Fatalf("load baseline: %v", err)
+	}
+	if len(base.Entries) != 2 {
+		t.Fatalf("expected two baseline entries, got %+v", base.Entries)
+	}
+
+	second := &crawler.Report{Errors: []crawler.Error{
+		{Source: "https://example.test/", Target: "https://example.test/a", Type: "http", Status: 410},
+		{Source: "https://example.test/", Target: "https://example.test/c", Type: "http", Status: 404},
+	}}
+	Apply(second, nil, base, now)
+
+	if len(second.Errors) != 1 || second.Errors[0].Target != "https://example.test/c" {
+		t.Fatalf("expected only the new error to remain, got %+v", second.Errors)
+	}
+	if len(second.Baselined) != 1 || second.Baselined[0].Target != "https://example.test/a" {
+		t.Fatalf("expected known error to be baselined, got %+v", second.Baselined)
+	}
+	if len(second.BaselineFixed) != 1 || second.BaselineFixed[0].Target != "https://example.test/b" {
+		t.Fatalf("expected fixed baseline entry, got %+v", second.BaselineFixed)
+	}
+	if !second.BaselineApplied {
+		t.Fatal("expected the report to record that a baseline was applied")
+	}
+}
+
+func TestBaselineEntryCoveredByIgnoreRuleIsNotFixed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	errs := []crawler.Error{{Source: "https://example.test/", Target: "https://legacy.test/a", Type: "http", Status: 404}}
+	base := New(&crawler.Report{Errors: errs}, now)
+
+	path := filepath.Join(t.TempDir(), "ignore.yaml")
+	if err := os.WriteFile(path, []byte("ignore:\n  - url: \"https://legacy.test/*\"\n"), 0o644); err != nil {
+		t.Fatalf("write ignore file: %v", err)
+	}
+	list, err := LoadIgnoreList(path)
+	if err != nil {
+		t.Fatalf("load ignore list: %v", err)
+	}
+
+	report := &crawler.Report{Errors: append([]crawler.Error(nil), errs...)}
+	Apply(report, list, base, now)
+	if len(report.Ignored) != 1 || !report.Ignored[0].Baselined || len(report.Baselined) != 0 || len(report.Errors) != 0 {
+		t.Fatalf("expected the error to be ignored, got %+v", report)
+	}
+	if len(report.BaselineFixed) != 0 {
+		t.Fatalf("an ignored error still occurs and must not be fixed, got %+v", report.BaselineFixed)
+	}
+}
+
+func TestLoadMissingBaseline(t *testing.T) {
+	base, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || base != nil {
+		t.Fatalf("expected nil baseline without error, got %+v, %v", base, err)
+	}
+}
@@ -0,0 +1,156 @@
+package baseline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"linkcheck/internal/crawler"
+	"linkcheck/internal/glob"
+)
+
+const expiryLayout = "2006-01-02"
+
+// IgnoreList is the parsed form of an ignore file:
+//
+//	ignore:
+//	  - url: "https://legacy.example.com/*"
+//	    source: "https://example.com/archive/*"
+//	    expires: 2025-12-31
+//	    reason: legacy section is being rewritten
+//	  - regex: '^https://example\.com/old/.*\.pdf$'
+//
+// URL and Source are globs where "*" matches any run of characters and "?"
+// matches one character. Regex is an alternative to URL. An entry stops
+// applying at the start of its expiry day.
+type IgnoreList struct {
+	Rules []IgnoreRule `yaml:"ignore"`
+}
+
+// IgnoreRule suppresses errors whose target matches URL or Regex and, when
+// Source is set, whose source page matches Source.
+type IgnoreRule struct {
+	URL     string `yaml:"url,omitempty"`
+	Regex   string `yaml:"regex,omitempty"`
+	Source  string `yaml:"source,omitempty"`
+	Expires string `yaml:"expires,omitempty"`
+	Reason  string `yaml:"reason,omitempty"`
+
+	target  *regexp.Regexp
+	source  *regexp.Regexp
+	expires time.Time
+}
+
+// LoadIgnoreList reads and validates an ignore file. A missing file yields an
+// empty list.
+func LoadIgnoreList(path string) (*IgnoreList, error) {
+	list := &IgnoreList{}
+	if path == "" {
+		return list, nil
+	}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(payload, list); err != nil {
+		return nil, fmt.Errorf("parse ignore file: %w", err)
+	}
+	if err := list.compile(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *IgnoreList) compile() error {
+	for i := range l.Rules {
+		if err := l.Rules[i].compile(); err != nil {
+			return fmt.Errorf("ignore rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *IgnoreRule) compile() error {
+	switch {
+	case r.URL != "" && r.Regex != "":
+		return errors.New("url and regex are mutually exclusive")
+	case r.URL != "":
+		r.target = glob.Compile(r.URL)
+	case r.Regex != "":
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		r.target = re
+	default:
+		return errors.New("url or regex is required")
+	}
+	if r.Source != "" {
+		r.source = glob.Compile(r.Source)
+	}
+	if r.Expires != "" {
+		expires, err := time.Parse(expiryLayout, r.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expires %q, want YYYY-MM-DD", r.Expires)
+		}
+		r.expires = expires
+	}
+	return nil
+}
+
+// Pattern returns the URL glob or regex the rule matches on.
+func (r IgnoreRule) Pattern() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return r.Regex
+}
+
+// Expired reports whether the rule no longer applies at now.
+func (r IgnoreRule) Expired(now time.Time) bool {
+	return !r.expires.IsZero() && !now.Before(r.expires)
+}
+
+func (r IgnoreRule) matches(err crawler.Error) bool {
+	if r.target == nil || !r.target.MatchString(err.Target) {
+		return false
+	}
+	return r.source == nil || r.source.MatchString(err.Source)
+}
+
+// match returns the first active rule matching err.
+func (l *IgnoreList) match(err crawler.Error, now time.Time) (IgnoreRule, bool) {
+	if l == nil {
+		return IgnoreRule{}, false
+	}
+	for _, rule := range l.Rules {
+		if rule.Expired(now) {
+			continue
+		}
+		if rule.matches(err) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
+
+// Expired returns the rules whose expiry date has passed at now.
+func (l *IgnoreList) Expired(now time.Time) []IgnoreRule {
+	if l == nil {
+		return nil
+	}
+	var expired []IgnoreRule
+	for _, rule := range l.Rules {
+		if rule.Expired(now) {
+			expired = append(expired, rule)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,146 @@
+package crawler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CheckAccessibility names the findings of AccessibilityCheck.
+const CheckAccessibility = "a11y"
+
+var (
+	startTagPattern   = regexp.MustCompile(`(?s)<([a-zA-Z][a-zA-Z0-9-]*)\b([^>]*)>`)
+	labelBlockPattern = regexp.MustCompile(`(?is)<label\b[^>]*>.*?</label\s*>`)
+	imgAltPattern     = regexp.MustCompile(`(?is)<img\b[^>]*\balt\s*=\s*("[^"]*\S[^"]*"|'[^']*\S[^']*'|[^\s"'>]+)`)
+)
+
+// genericLinkTexts are anchor texts that say nothing about the target.
+var genericLinkTexts = map[string]struct{}{
+	"click here": {},
+	"here":       {},
+	"click":      {},
+	"more":       {},
+	"read more":  {},
+	"learn more": {},
+	"link":       {},
+	"this link":  {},
+	"this page":  {},
+	"details":    {},
+}
+
+// unlabelledInputTypes are input types that need no label.
+var unlabelledInputTypes = map[string]struct{}{
+	"hidden": {},
+	"submit": {},
+	"reset":  {},
+	"button": {},
+	"image":  {},
+}
+
+// AccessibilityCheck reports basic WCAG problems: images without alt text,
+// links with empty or generic text, a missing lang attribute on <html>, form
+// fields without a label, skipped heading levels and duplicate ids.
+type AccessibilityCheck struct{}
+
+// Name implements PageCheck.
+func (AccessibilityCheck) Name() string { return CheckAccessibility }
+
+// CheckPage implements PageCheck.
+func (AccessibilityCheck) CheckPage(page *PageReport, body []byte) []Finding {
+	masked := maskHiddenMarkup(body)
+	lines := newLineIndex(masked)
+	var findings []Finding
+	add := func(rule, message string, start, end int) {
+		f := Finding{Rule: rule, Message: message, Severity: SeverityWarning}
+		if end > start {
+			f.Snippet = snippet(masked[start:end])
+			f.Line = lines.line(start)
+		}
+		findings = append(findings, f)
+	}
+
+	labelled := map[string]struct{}{}
+	var labelBlocks [][]int
+	for _, m := range labelBlockPattern.FindAllIndex(masked, -1) {
+		labelBlocks = append(labelBlocks, m)
+		tag := startTagPattern.FindSubmatch(masked[m[0]:m[1]])
+		if id := parseTagAttributes(tag[2])["for"]; id != "" {
+			labelled[id] = struct{}{}
+		}
+	}
+	insideLabel := func(offset int) bool {
+		for _, block := range labelBlocks {
+			if offset > block[0] && offset < block[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	ids := map[string]int{}
+	sawHTML := false
+	lastHeading := 0
+	for _, m := range startTagPattern.FindAllSubmatchIndex(masked, -1) {
+		name := strings.ToLower(string(masked[m[2]:m[3]]))
+		attrs := parseTagAttributes(masked[m[4]:m[5]])
+		if id := attrs["id"]; id != "" {
+			ids[id]++
+			if ids[id] == 2 {
+				add("duplicate-id", fmt.Sprintf("id %q is used more than once", id), m[0], m[1])
+			}
+		}
+		switch name {
+		case "html":
+			sawHTML = true
+			if attrs["lang"] == "" {
+				add("html-lang", "<html> has no lang attribute", m[0], m[1])
+			}
+		case "img":
+			if _, ok := attrs["alt"]; !ok && attrs["role"] != "presentation" && attrs["aria-hidden"] != "true" {
+				add("img-alt", "image has no alt attribute", m[0], m[1])
+			}
+		case "a":
+			if _, ok := attrs["href"]; !ok {
+				continue
+			}
+			inner := masked[m[1]:]
+			text := strings.ToLower(strings.Trim(anchorText(inner), " .:!>»→"))
+			label := attrs["aria-label"] + attrs["aria-labelledby"] + attrs["title"]
+			if closing := anchorClosePattern.FindIndex(inner); closing != nil && imgAltPattern.Match(inner[:closing[0]]) {
+				label += "img"
+			}
+			switch {
+			case text == "" && label == "":
+				add("link-text-empty", "link has no text or accessible name", m[0], m[1])
+			case label == "":
+				if _, generic := genericLinkTexts[text]; generic {
+					add("link-text-generic", fmt.Sprintf("link text %q does not describe the target", text), m[0], m[1])
+				}
+			}
+		case "input", "select", "textarea":
+			if name == "input" {
+				if _, ok := unlabelledInputTypes[strings.ToLower(attrs["type"])]; ok {
+					continue
+				}
+			}
+			if attrs["aria-label"] != "" || attrs["aria-labelledby"] != "" || attrs["title"] != "" || insideLabel(m[0]) {
+				continue
+			}
+			if _, ok := labelled[attrs["id"]]; ok && attrs["id"] != "" {
+				continue
+			}
+			add("input-label", fmt.Sprintf("<%s> has no label", name), m[0], m[1])
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			level := int(name[1] - '0')
+			if lastHeading > 0 && level > lastHeading+1 {
+				add("heading-skip", fmt.Sprintf("<h%d> follows <h%d>, skipping a level", level, lastHeading), m[0], m[1])
+			}
+			lastHeading = level
+		}
+	}
+	if !sawHTML {
+		add("html-lang", "page has no <html> element with a lang attribute", 0, 0)
+	}
+	return findings
+}
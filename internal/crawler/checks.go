@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// PageCheck inspects the body of every internal page that was fetched
+// successfully and reports findings alongside link errors. Checks are called
+// from several workers at once and must be safe for concurrent use.
+type PageCheck interface {
+	// Name identifies the check; it is stored in Finding.Check.
+	Name() string
+	// CheckPage returns the findings for one page. URL and Check of the
+	// returned findings are filled in when left empty.
+	CheckPage(page *PageReport, body []byte) []Finding
+}
+
+var hiddenMarkupPattern = regexp.MustCompile(`(?is)<!--.*?-->|<(?:script|style)\b[^>]*>(.*?)</(?:script|style)\s*>`)
+
+func (c *crawler) runChecks(page *PageReport, body []byte) {
+	if len(c.checks) == 0 {
+		return
+	}
+	var findings []Finding
+	for _, check := range c.checks {
+		for _, f := range check.CheckPage(page, body) {
+			if f.URL == "" {
+				f.URL = page.URL
+			}
+			if f.Check == "" {
+				f.Check = check.Name()
+			}
+			findings = append(findings, f)
+		}
+	}
+	if len(findings) == 0 {
+		return
+	}
+	c.reportMu.Lock()
+	c.findings = append(c.findings, findings...)
+	c.reportMu.Unlock()
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Line < b.Line
+	})
+}
+
+// maskHiddenMarkup blanks out comments and the contents of scripts and
+// styles so that markup patterns only match real elements. The <script> and
+// <style> tags themselves are kept, and so are offsets and line breaks.
+func maskHiddenMarkup(body []byte) []byte {
+	masked := append([]byte(nil), body...)
+	for _, m := range hiddenMarkupPattern.FindAllSubmatchIndex(masked, -1) {
+		start, end := m[0], m[1]
+		if m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		for i := start; i < end; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	}
+	return masked
+}
+
+// lineIndex maps byte offsets of a document to 1-based line numbers.
+type lineIndex []int
+
+func newLineIndex(body []byte) lineIndex {
+	var index lineIndex
+	for offset := 0; ; {
+		i := bytes.IndexByte(body[offset:], '\n')
+		if i < 0 {
+			return index
+		}
+		offset += i + 1
+		index = append(index, offset)
+	}
+}
+
+func (li lineIndex) line(offset int) int {
+	return sort.SearchInts(li, offset+1) + 1
+}
+
+// snippet shortens markup quoted in a finding.
+func snippet(markup []byte) string {
+	text := collapseUnicodeSpaces(string(markup))
+	if utf8.RuneCountInString(text) <= 120 {
+		return text
+	}
+	return string([]rune(text)[:117]) + "..."
+}
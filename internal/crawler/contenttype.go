@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// defaultParseableTypes are the media types whose bodies are scanned for links
+// when Config.ParseableTypes is empty.
+var defaultParseableTypes = []string{"text/html", "application/xhtml+xml"}
+
+// staticMediaTypes maps the extensions of static files to the media type they
+// are expected to be served as. The table is fixed rather than read from the
+// system's MIME database, which differs between hosts and registers server
+// script extensions such as .php whose output is usually HTML.
+var staticMediaTypes = map[string]string{
+	".avif":  "image/avif",
+	".css":   "text/css",
+	".csv":   "text/csv",
+	".gif":   "image/gif",
+	".htm":   "text/html",
+	".html":  "text/html",
+	".ico":   "image/x-icon",
+	".jpeg":  "image/jpeg",
+	".jpg":   "image/jpeg",
+	".js":    "text/javascript",
+	".json":  "application/json",
+	".mjs":   "text/javascript",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".pdf":   "application/pdf",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".txt":   "text/plain",
+	".wasm":  "application/wasm",
+	".webm":  "video/webm",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".xhtml": "application/xhtml+xml",
+	".xml":   "application/xml",
+	".zip":   "application/zip",
+}
+
+// mediaTypeAliases maps media types to the name they are compared under, so
+// that equivalent registrations do not count as unexpected.
+var mediaTypeAliases = map[string]string{
+	"application/xhtml+xml":    "text/html",
+	"application/javascript":   "text/javascript",
+	"application/x-javascript": "text/javascript",
+	"text/xml":                 "application/xml",
+	"image/vnd.microsoft.icon": "image/x-icon",
+}
+
+func buildParseableTypes(list []string) map[string]struct{} {
+	if len(list) == 0 {
+		list = defaultParseableTypes
+	}
+	parseable := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		if mediaType := normalizeMediaType(item); mediaType != "" {
+			parseable[mediaType] = struct{}{}
+		}
+	}
+	return parseable
+}
+
+// normalizeMediaType lower-cases a Content-Type value and drops its
+// parameters. It returns "" for values that are not a type/subtype pair.
+func normalizeMediaType(value string) string {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return ""
+	}
+	return mediaType
+}
+
+// responseMediaType returns the media type of a response. When the
+// Content-Type header is missing or malformed the body is sniffed, and
+// sniffed is set.
+func responseMediaType(header http.Header, body []byte) (mediaType string, sniffed bool) {
+	if mediaType := normalizeMediaType(header.Get("Content-Type")); mediaType != "" {
+		return mediaType, false
+	}
+	return normalizeMediaType(http.DetectContentType(body)), true
+}
+
+// checkContentType reports pages sent without a usable Content-Type header
+// and pages whose media type does not match their file extension, such as an
+// .html page served as text/plain.
+func (c *crawler) checkContentType(page *PageReport, sniffed bool) {
+	target := page.URL
+	if page.RedirectURL != "" {
+		target = page.RedirectURL
+	}
+	var msg string
+	if sniffed {
+		msg = fmt.Sprintf("no valid Content-Type header; content sniffed as %s", page.ContentType)
+	} else if expected := extensionMediaType(target); expected != "" && !sameMediaType(expected, page.ContentType) {
+		msg = fmt.Sprintf("served as %s, but the extension suggests %s", page.ContentType, expected)
+	}
+	if msg == "" {
+		return
+	}
+	c.recordError(Error{Source: page.URL, Target: target, Type: "content-type", Message: msg, Status: page.Status})
+}
+
+// extensionMediaType returns the media type expected for the static file
+// extension of rawURL, or "" when the path has no extension or it is not in
+// staticMediaTypes.
+func extensionMediaType(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return staticMediaTypes[strings.ToLower(path.Ext(u.Path))]
+}
+
+func sameMediaType(a, b string) bool {
+	if alias, ok := mediaTypeAliases[a]; ok {
+		a = alias
+	}
+	if alias, ok := mediaTypeAliases[b]; ok {
+		b = alias
+	}
+	return a == b
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,9 +19,13 @@ type crawler struct {
 	maxPages          int
 	maxDepth          int
 	allowedExt        map[string]struct{}
+	parseableTypes    map[string]struct{}
 	ignoreRobots      bool
+	obeyMetaRobots    bool
+	skipRelNofollow   bool
 	cachePath         string
 	requestsPerMinute int
+	retries           int
 	progress          func(string)
 	markdownDir       string
 
@@ -32,13 +37,19 @@ type crawler struct {
 
 	visitedInternal map[string]struct{}
 	visitedExternal map[string]struct{}
+	skipped         map[string]SkipReason
 	robots          map[string]*robotsGroup
 
-	mu       sync.Mutex
-	reportMu sync.Mutex
-	pages    map[string]*PageReport
-	errors   []Error
-	stats    Stats
+	// mu guards the visited sets, skipped and stats; reportMu guards the
+	// collected results below. Code holding both takes mu first.
+	mu        sync.Mutex
+	reportMu  sync.Mutex
+	pages     map[string]*PageReport
+	errors    []Error
+	unvisited []string
+	findings  []Finding
+	referrers map[string][]Referrer
+	stats     Stats
 
 	cacheMu       sync.RWMutex
 	cache         cacheData
@@ -46,15 +57,44 @@ type crawler struct {
 	boilerplateMu sync.Mutex
 	boilerplates  map[string]*boilerplateInfo
 
+	soft404Title        []soft404Pattern
+	soft404Body         []soft404Pattern
+	soft404Probe        bool
+	soft404Mu           sync.Mutex
+	soft404Fingerprints map[string]*soft404Host
+
+	auditSEO     bool
+	auditHeaders bool
+	checks       []PageCheck
+
+	measurePerformance bool
+
+	checkMixed   bool
+	probeHTTPS   bool
+	followCSS    bool
+	relations    bool
+	cssMu        sync.Mutex
+	cssChecked   map[string]struct{}
+	httpsMu      sync.Mutex
+	httpsChecked map[string]httpsAvailability
+
+	checkTLS bool
+	tlsMu    sync.Mutex
+	tlsHosts map[string]*tlsObservation
+
 	rateLimiter chan struct{}
 	rateTicker  *time.Ticker
 
 	robotsMu sync.Mutex
 }
 
+// externalJob is a status check. asset marks internal resources referenced
+// from CSS, which are not counted as external links.
 type externalJob struct {
 	url    string
 	source string
+	via    *Link
+	asset  bool
 }
 
 // Crawl performs the crawl using the provided configuration and returns a report.
@@ -101,6 +141,11 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 		cfg.RequestsPerMinute = 60
 	}
 
+	retries := cfg.Retries
+	if retries < 0 {
+		retries = 0
+	}
+
 	maxDepth := cfg.MaxDepth
 	if maxDepth < 0 {
 		maxDepth = -1
@@ -108,6 +153,15 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 
 	allowedExt := buildAllowedExtensions(cfg.AllowedExtensions)
 
+	soft404Title, err := compileSoft404Patterns(cfg.Soft404TitlePatterns)
+	if err != nil {
+		return nil, err
+	}
+	soft404Body, err := compileSoft404Patterns(cfg.Soft404BodyPatterns)
+	if err != nil {
+		return nil, err
+	}
+
 	cachePath := cfg.CachePath
 
 	cacheData, err := loadCache(cachePath)
@@ -122,18 +176,48 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 		maxPages:          cfg.MaxPages,
 		maxDepth:          maxDepth,
 		allowedExt:        allowedExt,
+		parseableTypes:    buildParseableTypes(cfg.ParseableTypes),
 		ignoreRobots:      cfg.IgnoreRobots,
+		obeyMetaRobots:    !cfg.IgnoreRobots && !cfg.IgnoreMetaRobots,
+		skipRelNofollow:   cfg.SkipNofollowLinks,
 		cachePath:         cachePath,
 		requestsPerMinute: cfg.RequestsPerMinute,
+		retries:           retries,
 		internalJobs:      make(chan internalJob, maxWorkers*2),
 		visitedInternal:   map[string]struct{}{},
 		visitedExternal:   map[string]struct{}{},
+		skipped:           map[string]SkipReason{},
 		pages:             map[string]*PageReport{},
+		referrers:         map[string][]Referrer{},
 		robots:            map[string]*robotsGroup{},
 		cache:             cacheData,
 		progress:          cfg.Progress,
 		markdownDir:       strings.TrimSpace(cfg.MarkdownDir),
 		boilerplates:      map[string]*boilerplateInfo{},
+
+		soft404Title:        soft404Title,
+		soft404Body:         soft404Body,
+		soft404Probe:        cfg.Soft404Probe,
+		soft404Fingerprints: map[string]*soft404Host{},
+
+		auditSEO:     cfg.AuditSEO,
+		auditHeaders: cfg.AuditSecurityHeaders,
+		checks:       append([]PageCheck(nil), cfg.Checks...),
+
+		measurePerformance: cfg.MeasurePerformance || cfg.TTFBBudget > 0 || cfg.PageWeightBudget > 0,
+
+		checkMixed:   cfg.CheckMixedContent,
+		probeHTTPS:   cfg.ProbeHTTPS,
+		followCSS:    cfg.CheckCSS,
+		relations:    cfg.CheckRelations,
+		cssChecked:   map[string]struct{}{},
+		httpsChecked: map[string]httpsAvailability{},
+
+		checkTLS: cfg.CheckTLS,
+		tlsHosts: map[string]*tlsObservation{},
+	}
+	if cfg.AuditAccessibility {
+		c.checks = append(c.checks, AccessibilityCheck{})
 	}
 
 	if cachePath != "" {
@@ -145,11 +229,17 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 		c.cacheMu.Unlock()
 	}
 
-	if cfg.AllowExternal {
+	statusChecks := cfg.AllowExternal || cfg.CheckCSS || cfg.CheckRelations
+	if statusChecks {
 		c.externalJobs = make(chan externalJob, maxWorkers)
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	var cancel context.CancelFunc
+	if cfg.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
 	defer cancel()
 	defer c.shutdownRateLimiter()
 	c.setupRateLimiter(ctx, maxWorkers)
@@ -162,7 +252,7 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 	if externalWorkers < 2 {
 		externalWorkers = 2
 	}
-	if !cfg.AllowExternal {
+	if !statusChecks {
 		externalWorkers = 0
 	}
 	for i := 0; i < externalWorkers; i++ {
@@ -170,31 +260,91 @@ func Crawl(ctx context.Context, cfg Config) (*Report, error) {
 	}
 
 	started := time.Now()
-	c.enqueueInternal(parsed.String(), 0)
+	c.enqueueInternal(ctx, parsed.String(), 0)
+	sitemap := c.sitemapURLs(cfg.Sitemap)
+	for _, u := range sitemap {
+		c.enqueueInternal(ctx, u, 0)
+	}
 
 	c.internalWG.Wait()
 	close(c.internalJobs)
 
-	if cfg.AllowExternal {
+	if statusChecks {
 		c.externalWG.Wait()
 		close(c.externalJobs)
 	}
 
 	finished := time.Now()
 
+	sort.Strings(c.unvisited)
+	// The context may end after the last job finished; the crawl is only
+	// partial when work was actually abandoned.
+	partial := len(c.unvisited) > 0
 	report := &Report{
+		StartURL:   parsed.String(),
+		Sitemap:    sitemap,
 		Pages:      c.pages,
 		Errors:     c.errors,
 		Stats:      c.collectStats(finished.Sub(started)),
 		StartedAt:  started,
 		FinishedAt: finished,
+		Partial:    partial,
+		Cancelled:  partial && errors.Is(ctx.Err(), context.Canceled),
+		Unvisited:  c.unvisited,
+		Skipped:    c.collectSkipped(),
+		Referrers:  c.collectReferrers(),
+		NoIndex:    c.collectNoIndex(),
+	}
+	report.Structure = AnalyzeStructure(report)
+	report.Duplicates = FindDuplicates(report.Pages, DefaultNearDuplicateDistance)
+	report.Findings = c.findings
+	if cfg.AuditSEO {
+		report.Findings = append(report.Findings, AuditSEO(report)...)
+	}
+	if cfg.CheckRelations {
+		report.Findings = append(report.Findings, AuditHreflang(report)...)
+	}
+	report.TLS = c.collectTLS()
+	if cfg.CheckTLS {
+		window := cfg.TLSExpiryWindow
+		if window <= 0 {
+			window = defaultTLSExpiryWindow
+		}
+		report.Findings = append(report.Findings, tlsFindings(report.TLS, finished, window)...)
 	}
+	if cfg.AuditSecurityHeaders {
+		groups, findings := AuditSecurityHeaders(report, cfg.SecurityHeaderPrefixDepth)
+		report.Headers = groups
+		report.Findings = append(report.Findings, findings...)
+	}
+	if c.measurePerformance {
+		groups, findings := AuditPerformance(report, cfg.PerformancePrefixDepth, cfg.TTFBBudget, cfg.PageWeightBudget)
+		report.Performance = groups
+		report.Findings = append(report.Findings, findings...)
+	}
+	sortFindings(report.Findings)
 	if err := c.writeCache(); err != nil {
 		return nil, fmt.Errorf("write cache: %w", err)
 	}
 	return report, nil
 }
 
+// sitemapURLs normalizes the sitemap entries on the crawled host and drops
+// the rest.
+func (c *crawler) sitemapURLs(entries []string) []string {
+	var urls []string
+	for _, entry := range entries {
+		normalized := c.normalizeURL(entry)
+		if normalized == "" {
+			continue
+		}
+		if parsed, err := url.Parse(normalized); err == nil && strings.EqualFold(parsed.Host, c.start.Host) {
+			urls = append(urls, normalized)
+		}
+	}
+	return urls
+}
+
 func (c *crawler) emitProgress(u string) {
 	if c.progress == nil {
 		return
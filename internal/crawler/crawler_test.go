@@ -1,16 +1,27 @@
 package crawler
 
 import (
+	"bytes"
+	"compress/zlib"
 	"context"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"maps"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -91,6 +102,9 @@ func TestCrawlRespectsMaxDepth(t *testing.T) {
 	if limited.Stats.SkippedByDepth == 0 {
 		t.Fatalf("expected skipped-by-depth counter to increment")
 	}
+	if len(limited.Skipped) != 1 || limited.Skipped[0] != (Skip{URL: "https://example.test/level/2", Reason: SkipReasonDepth}) {
+		t.Fatalf("expected level 2 page to be listed as skipped by depth, got %+v", limited.Skipped)
+	}
 
 	unbounded, err := Crawl(context.Background(), Config{
 		StartURL:          "https://example.test/start",
@@ -395,60 +409,1787 @@ func TestMetaRefreshLinkIsFollowed(t *testing.T) {
 	}
 }
 
-func appendToFile(path, data string) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+func TestCrawlReturnsPartialReportAfterMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: slowTransport{fakeTransport: &fakeTransport{linkCount: 20}, delay: time.Second},
+	}
+
+	resultCh := make(chan crawlResult, 1)
+	go func() {
+		report, err := Crawl(context.Background(), Config{
+			StartURL:          "https://example.test/start",
+			MaxWorkers:        2,
+			Client:            client,
+			Timeout:           5 * time.Second,
+			RequestsPerMinute: 60000,
+			MaxDepth:          -1,
+			IgnoreRobots:      true,
+			MaxDuration:       200 * time.Millisecond,
+		})
+		resultCh <- crawlResult{report: report, err: err}
+	}()
+
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("crawl did not stop after max duration")
+	case res := <-resultCh:
+		if res.err != nil {
+			t.Fatalf("crawl failed: %v", res.err)
+		}
+		if !res.report.Partial {
+			t.Fatal("expected report to be marked partial")
+		}
+		if res.report.Cancelled {
+			t.Fatal("expected deadline, not cancellation")
+		}
+		if _, ok := res.report.Pages["https://example.test/start"]; !ok {
+			t.Fatal("expected start page in partial report")
+		}
+		if len(res.report.Unvisited) == 0 {
+			t.Fatal("expected unvisited URLs in partial report")
+		}
+		for _, err := range res.report.Errors {
+			if err.Type == "request" || err.Type == "rate" {
+				t.Fatalf("expected abandoned jobs not to be reported as errors, got %+v", err)
+			}
+		}
+	}
+}
+
+func TestCrawlMarksCancelledReport(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: slowTransport{fakeTransport: &fakeTransport{linkCount: 5}, delay: time.Second},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	report, err := Crawl(ctx, Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        1,
+		Client:            client,
+		Timeout:           5 * time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+	})
 	if err != nil {
-		return err
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if !report.Partial || !report.Cancelled {
+		t.Fatalf("expected partial cancelled report, got partial=%v cancelled=%v", report.Partial, report.Cancelled)
+	}
+	if len(report.Unvisited) != 5 {
+		t.Fatalf("expected all detail pages to be unvisited, got %v", report.Unvisited)
+	}
+	if report.Stats.PagesVisited != 1 {
+		t.Fatalf("expected only the start page to count as visited, got %d", report.Stats.PagesVisited)
 	}
-	defer f.Close()
-	_, err = f.WriteString(data)
-	return err
 }
 
-type fakeTransport struct {
-	linkCount int
+func TestCrawlCancelledAfterLastJobIsComplete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	report, err := Crawl(ctx, Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        1,
+		Client:            &http.Client{Timeout: time.Second, Transport: cancelTransport{cancel: cancel}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		IgnoreRobots:      true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if report.Partial || report.Cancelled || len(report.Unvisited) != 0 {
+		t.Fatalf("expected a crawl without abandoned work to be complete, got partial=%v cancelled=%v unvisited=%v", report.Partial, report.Cancelled, report.Unvisited)
+	}
 }
 
-type emptyContentTransport struct{}
-type depthTransport struct {
-	maxLevel int
+func TestBrokenTargetsListEveryReferrer(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{
+		Timeout:   time.Second,
+		Transport: referrerTransport{},
+	}
+
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		AllowExternal:     true,
+		MaxWorkers:        1,
+		Client:            client,
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	broken := report.BrokenTargets()
+	if len(broken) != 2 {
+		t.Fatalf("expected two broken targets, got %+v", broken)
+	}
+	external := broken[0]
+	if external.URL != "https://dead.test/sdk" || external.Status != http.StatusNotFound {
+		t.Fatalf("unexpected external broken target: %+v", external)
+	}
+	want := []Referrer{
+		{Source: "https://example.test/a", Text: "Download the SDK", Line: 3},
+		{Source: "https://example.test/b", Text: "SDK", Line: 2},
+	}
+	if len(external.Referrers) != len(want) {
+		t.Fatalf("expected %d referrers, got %+v", len(want), external.Referrers)
+	}
+	for i := range want {
+		if external.Referrers[i] != want[i] {
+			t.Fatalf("referrer %d: expected %+v, got %+v", i, want[i], external.Referrers[i])
+		}
+	}
+
+	internal := broken[1]
+	if internal.URL != "https://example.test/missing" || len(internal.Referrers) != 2 {
+		t.Fatalf("expected internal broken target with two referrers, got %+v", internal)
+	}
+
+	for _, e := range report.Errors {
+		if e.Target == "https://dead.test/sdk" && (e.Link == nil || e.Link.Text == "") {
+			t.Fatalf("expected external error to carry the referring link, got %+v", e)
+		}
+	}
 }
 
-func (ft *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if req.URL.Host != "example.test" {
-		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+func TestCanonicalSharedBySEOAndRelations(t *testing.T) {
+	start, _ := url.Parse("https://example.test/")
+	c := &crawler{start: start}
+
+	body := []byte(`<head>
+<!-- <link rel="canonical" href="/draft"> -->
+<link rel="canonical" href="mailto:team@example.test">
+<link href="/guide#intro" rel="Canonical">
+<link rel="canonical" href="/other">
+</head>`)
+	meta := c.extractSEOMetadata(body, "https://example.test/docs/")
+	relations, links := c.extractRelations(body, "https://example.test/docs/")
+	if meta.Canonical != "https://example.test/guide" || relations == nil || relations.Canonical != meta.Canonical {
+		t.Fatalf("expected one canonical URL, got SEO %q and relations %+v", meta.Canonical, relations)
+	}
+	if len(links) != 1 || links[0].Line != 4 || !links[0].HasRel("canonical") {
+		t.Fatalf("unexpected canonical link: %+v", links)
 	}
+}
 
-	switch req.URL.Path {
-	case "/robots.txt":
-		return newStringResponse(req, http.StatusNotFound, ""), nil
-	case "/start":
-		markup := buildListingPageHTML(ft.linkCount)
-		return newStringResponse(req, http.StatusOK, markup), nil
-	case "/meta-redirect":
-		markup := "<!doctype html><html><head><title>Redirecting</title><meta http-equiv=\"refresh\" content=\"0; url=/page/0\"></head><body><p>Redirecting...</p></body></html>"
-		return newStringResponse(req, http.StatusOK, markup), nil
-	default:
-		if strings.HasPrefix(req.URL.Path, "/page/") {
-			pageID := strings.TrimPrefix(req.URL.Path, "/page/")
-			markup := buildDetailPageHTML(pageID)
-			return newStringResponse(req, http.StatusOK, markup), nil
+func TestExtractLinksCapturesAnchorAttributes(t *testing.T) {
+	start, _ := url.Parse("https://example.test/")
+	c := &crawler{start: start}
+
+	body := []byte(`<p>
+<a class="btn" href="/sdk.html" rel="NoFollow sponsored" target=_blank title="Get &amp; install">Download <em>the</em> SDK</a>
+<a href='https://other.test/' rel=ugc>Other</a>
+</p>`)
+	links := c.extractLinks(body, "https://example.test/docs/")
+	if len(links) != 2 {
+		t.Fatalf("expected two links, got %+v", links)
+	}
+	sdk := links[0]
+	if sdk.URL != "https://example.test/sdk.html" || sdk.Text != "Download the SDK" || sdk.Line != 2 {
+		t.Fatalf("unexpected link: %+v", sdk)
+	}
+	if !sdk.HasRel("nofollow") || !sdk.HasRel("sponsored") || sdk.Target != "_blank" || sdk.Title != "Get & install" || sdk.Element != LinkElementAnchor {
+		t.Fatalf("unexpected link attributes: %+v", sdk)
+	}
+	if other := links[1]; other.Type != LinkTypeExternal || !other.HasRel("ugc") || other.Line != 3 {
+		t.Fatalf("unexpected external link: %+v", other)
+	}
+}
+
+func TestMarkdownFrontmatterListsLinks(t *testing.T) {
+	page := &PageReport{
+		URL:    "https://example.test/",
+		Status: http.StatusOK,
+		Links: []Link{{
+			URL:     "https://example.test/sdk.html",
+			Type:    LinkTypeInternal,
+			Element: LinkElementAnchor,
+			Text:    "Download the SDK",
+			Rel:     []string{"nofollow"},
+		}},
+	}
+	content := buildMarkdownDocument(page, "body", time.Now(), "hash", 1, 0)
+	for _, want := range []string{"links:\n", `  - url: "https://example.test/sdk.html"`, `    text: "Download the SDK"`, `    rel: ["nofollow"]`} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in frontmatter, got %q", want, content)
 		}
-		return newStringResponse(req, http.StatusNotFound, ""), nil
 	}
 }
 
-func (emptyContentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if req.URL.Host != "example.test" {
-		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+func TestCrawlRetriesServerErrors(t *testing.T) {
+	retryBackoff = time.Millisecond
+
+	transport := &flakyTransport{failures: 2}
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        1,
+		Client:            &http.Client{Timeout: time.Second, Transport: transport},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+		Retries:           2,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if len(report.Errors) != 0 {
+		t.Fatalf("expected retries to recover from server errors, got %+v", report.Errors)
 	}
+	if got := transport.calls.Load(); got != 3 {
+		t.Fatalf("expected three attempts, got %d", got)
+	}
+}
 
-	switch req.URL.Path {
-	case "/robots.txt":
-		return newStringResponse(req, http.StatusNotFound, ""), nil
-	case "/start":
-		markup := "<!doctype html><html><head><title>Empty Page Title</title><meta name=\"description\" content=\"This description summarises the page.\"></head><body></body></html>"
-		return newStringResponse(req, http.StatusOK, markup), nil
+func TestCrawlAnalyzesStructure(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: structureTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+		Sitemap:           []string{"https://example.test/a", "/orphan", "https://elsewhere.test/x"},
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	s := report.Structure
+	if s == nil {
+		t.Fatal("expected structure analysis on report")
+	}
+	want := map[string]PageStructure{
+		"https://example.test/start":  {URL: "https://example.test/start", Depth: 0, Inbound: 1, Outbound: 2},
+		"https://example.test/a":      {URL: "https://example.test/a", Depth: 1, Inbound: 2, Outbound: 2},
+		"https://example.test/new":    {URL: "https://example.test/new", Depth: 1, Inbound: 2, Outbound: 0},
+		"https://example.test/orphan": {URL: "https://example.test/orphan", Depth: -1, Inbound: 0, Outbound: 1},
+	}
+	if len(s.Pages) != len(want) {
+		t.Fatalf("unexpected structure pages: %+v", s.Pages)
+	}
+	for _, page := range s.Pages {
+		if want[page.URL] != page {
+			t.Errorf("unexpected structure for %s: %+v", page.URL, page)
+		}
+	}
+	if got := strings.Join(s.DeadEnds, ","); got != "https://example.test/new" {
+		t.Fatalf("unexpected dead ends: %v", s.DeadEnds)
+	}
+	if got := strings.Join(s.RedirectOnly, ","); got != "https://example.test/new" {
+		t.Fatalf("unexpected redirect-only pages: %v", s.RedirectOnly)
+	}
+	if got := strings.Join(s.Orphans, ","); got != "https://example.test/orphan" {
+		t.Fatalf("unexpected orphans: %v", s.Orphans)
+	}
+}
+
+func TestCrawlSeedsSitemapEntriesAtDepthZero(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: structureTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          0,
+		IgnoreRobots:      true,
+		Sitemap:           []string{"/orphan"},
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	page, ok := report.Pages["https://example.test/orphan"]
+	if !ok || page.Depth != 0 {
+		t.Fatalf("expected the sitemap entry to be crawled at depth 0, got %+v", page)
+	}
+	if _, ok := report.Pages["https://example.test/a"]; ok {
+		t.Fatalf("MaxDepth must limit links followed from sitemap seeds")
+	}
+	if len(report.Skipped) != 2 || report.Skipped[0].URL != "https://example.test/a" || report.Skipped[0].Reason != SkipReasonDepth {
+		t.Fatalf("unexpected skips: %+v", report.Skipped)
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	locs, children, err := ParseSitemap(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.test/a </loc></url>
+  <url><loc>https://example.test/b</loc><lastmod>2024-01-01</lastmod></url>
+</urlset>`))
+	if err != nil {
+		t.Fatalf("parse sitemap: %v", err)
+	}
+	if strings.Join(locs, ",") != "https://example.test/a,https://example.test/b" || len(children) != 0 {
+		t.Fatalf("unexpected locations: %v, %v", locs, children)
+	}
+
+	locs, children, err = ParseSitemap(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.test/sitemap-pages.xml</loc></sitemap>
+  <sitemap><loc>https://example.test/sitemap-posts.xml</loc></sitemap>
+</sitemapindex>`))
+	if err != nil {
+		t.Fatalf("parse sitemap index: %v", err)
+	}
+	if len(locs) != 0 || strings.Join(children, ",") != "https://example.test/sitemap-pages.xml,https://example.test/sitemap-posts.xml" {
+		t.Fatalf("child sitemaps must not be returned as pages: %v, %v", locs, children)
+	}
+}
+
+func TestCrawlDetectsSoft404s(t *testing.T) {
+	crawl := func(cfg Config) *Report {
+		t.Helper()
+		cfg.StartURL = "https://example.test/start"
+		cfg.MaxWorkers = 2
+		cfg.Client = &http.Client{Timeout: time.Second, Transport: soft404Transport{}}
+		cfg.Timeout = time.Second
+		cfg.RequestsPerMinute = 60000
+		cfg.MaxDepth = -1
+		report, err := Crawl(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		return report
+	}
+	soft404Targets := func(r *Report) []string {
+		var targets []string
+		for _, e := range r.Errors {
+			if e.Type == "soft404" {
+				targets = append(targets, e.Target)
+			}
+		}
+		sort.Strings(targets)
+		return targets
+	}
+
+	probed := crawl(Config{Soft404Probe: true, IgnoreRobots: true})
+	if got := strings.Join(soft404Targets(probed), ","); got != "https://example.test/gone" {
+		t.Fatalf("unexpected soft 404s in probe mode: %v (errors %+v)", got, probed.Errors)
+	}
+	if page := probed.Pages["https://example.test/gone"]; page == nil || page.Status != http.StatusOK || page.Error == "" {
+		t.Fatalf("expected soft 404 to be recorded on the page, got %+v", page)
+	}
+
+	// robots.txt disallows the probe path, so the host is not probed.
+	disallowed := crawl(Config{Soft404Probe: true})
+	if got := soft404Targets(disallowed); len(got) != 0 {
+		t.Fatalf("expected no probe where robots.txt disallows it, got %v", got)
+	}
+
+	patterned := crawl(Config{IgnoreRobots: true, Soft404TitlePatterns: []string{"not found"}, Soft404BodyPatterns: []string{"no longer available"}})
+	if got := strings.Join(soft404Targets(patterned), ","); got != "https://example.test/gone,https://example.test/retired" {
+		t.Fatalf("unexpected soft 404s in pattern mode: %v", got)
+	}
+
+	if _, err := Crawl(context.Background(), Config{StartURL: "https://example.test/", Soft404TitlePatterns: []string{"("}}); err == nil {
+		t.Fatal("expected invalid pattern to be rejected")
+	}
+}
+
+func TestCrawlFindsDuplicateContent(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: duplicateTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	for u, page := range report.Pages {
+		if page.ContentHash == "" || page.SimHash == 0 {
+			t.Fatalf("expected content fingerprints without markdown export for %s: %+v", u, page)
+		}
+	}
+	if len(report.Duplicates) != 2 {
+		t.Fatalf("expected two duplicate clusters, got %+v", report.Duplicates)
+	}
+	exact := report.Duplicates[0]
+	if exact.Kind != DuplicateExact || exact.ContentHash == "" || strings.Join(exact.Pages, ",") != "https://example.test/copy-a,https://example.test/copy-b" {
+		t.Fatalf("unexpected exact cluster: %+v", exact)
+	}
+	near := report.Duplicates[1]
+	if near.Kind != DuplicateNear || strings.Join(near.Pages, ",") != "https://example.test/draft,https://example.test/final" {
+		t.Fatalf("unexpected near cluster: %+v", near)
+	}
+	if clusters := FindDuplicates(report.Pages, -1); len(clusters) != 1 {
+		t.Fatalf("expected near duplicates to be disabled, got %+v", clusters)
+	}
+}
+
+func TestCrawlFingerprintsPagesWithoutTheirLayout(t *testing.T) {
+	dir := t.TempDir()
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        1,
+		Client:            &http.Client{Timeout: time.Second, Transport: layoutTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          1,
+		IgnoreRobots:      true,
+		MarkdownDir:       dir,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if len(report.Duplicates) != 0 {
+		t.Fatalf("expected distinct articles sharing a layout not to cluster, got %+v", report.Duplicates)
+	}
+	for u, page := range report.Pages {
+		if page.MarkdownPath == "" {
+			continue
+		}
+		hash, err := readMarkdownHash(page.MarkdownPath)
+		if err != nil || hash != page.ContentHash {
+			t.Fatalf("expected %s to carry the content_sha256 of its export, got %q and %q (%v)", u, page.ContentHash, hash, err)
+		}
+	}
+}
+
+func TestCrawlAuditsSEOMetadata(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: seoTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          -1,
+		IgnoreRobots:      true,
+		AuditSEO:          true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	start := report.Pages["https://example.test/start"].SEO
+	if start == nil {
+		t.Fatal("expected SEO metadata on start page")
+	}
+	if start.Title != "Home" || start.Description != "Welcome to the example site." || start.Lang != "en" || start.H1Count != 1 {
+		t.Fatalf("unexpected metadata: %+v", start)
+	}
+	if start.Canonical != "https://example.test/start" || start.OpenGraph["og:title"] != "Example" {
+		t.Fatalf("unexpected canonical or Open Graph tags: %+v", start)
+	}
+
+	var got []string
+	for _, f := range report.Findings {
+		if f.Check != CheckSEO || f.Severity != SeverityWarning {
+			t.Fatalf("unexpected finding: %+v", f)
+		}
+		got = append(got, strings.TrimPrefix(f.URL, "https://example.test")+" "+f.Rule)
+	}
+	want := []string{
+		"/a canonical-error",
+		"/a canonical-not-self",
+		"/a description-missing",
+		"/a h1-multiple",
+		"/a title-duplicate",
+		"/a title-too-long",
+		"/b description-missing",
+		"/b h1-missing",
+		"/b title-duplicate",
+		"/b title-too-long",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestAccessibilityCheck(t *testing.T) {
+	body := []byte(`<!doctype html>
+<html>
+<body>
+<h1 id="top">Title</h1>
+<h3>Skipped</h3>
+<img src="/logo.png">
+<img src="/spacer.gif" alt="">
+<a href="/a">Click here</a>
+<a href="/b"></a>
+<a href="/c"><img src="/c.png" alt="Catalogue"></a>
+<a href="/d" aria-label="Downloads">here</a>
+<!-- <img src="/commented.png"> -->
+<label for="email">Email</label><input id="email" type="email">
+<label>Name <input type="text" name="name"></label>
+<input type="text" name="q">
+<input type="hidden" name="token">
+<textarea id="top"></textarea>
+</body>
+</html>`)
+	var got []string
+	for _, f := range (AccessibilityCheck{}).CheckPage(&PageReport{URL: "https://example.test/"}, body) {
+		got = append(got, fmt.Sprintf("%d %s", f.Line, f.Rule))
+	}
+	want := []string{
+		"2 html-lang",
+		"5 heading-skip",
+		"6 img-alt",
+		"8 link-text-generic",
+		"9 link-text-empty",
+		"15 input-label",
+		"17 duplicate-id",
+		"17 input-label",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+type countingCheck struct {
+	pages atomic.Int64
+}
+
+func (*countingCheck) Name() string { return "count" }
+
+func (cc *countingCheck) CheckPage(page *PageReport, body []byte) []Finding {
+	cc.pages.Add(1)
+	return []Finding{{Rule: "seen", Message: "page checked"}}
+}
+
+func TestCrawlRunsPageChecks(t *testing.T) {
+	check := &countingCheck{}
+	report, err := Crawl(context.Background(), Config{
+		StartURL:           "https://example.test/start",
+		MaxWorkers:         2,
+		Client:             &http.Client{Timeout: time.Second, Transport: referrerTransport{}},
+		Timeout:            time.Second,
+		RequestsPerMinute:  60000,
+		MaxDepth:           -1,
+		IgnoreRobots:       true,
+		Checks:             []PageCheck{check},
+		AuditAccessibility: true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if got := check.pages.Load(); got != 3 {
+		t.Fatalf("expected the check to run on three healthy pages, got %d", got)
+	}
+	counts := map[string]int{}
+	for _, f := range report.Findings {
+		counts[f.Check]++
+		if f.URL == "" {
+			t.Fatalf("expected finding URL to be filled in: %+v", f)
+		}
+	}
+	if counts["count"] != 3 || counts[CheckAccessibility] == 0 {
+		t.Fatalf("unexpected findings per check: %v", counts)
+	}
+}
+
+func TestCrawlReportsMixedContent(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: mixedContentTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          0,
+		IgnoreRobots:      true,
+		CheckMixedContent: true,
+		ProbeHTTPS:        true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	var got []string
+	for _, e := range report.Errors {
+		got = append(got, e.Type+" "+e.Message)
+	}
+	sort.Strings(got)
+	want := []string{
+		"insecure-link https page links to insecure http://example.test/about; https://example.test/about is available",
+		"mixed-content active mixed content: <script> loads http://cdn.test/app.js over http; no https equivalent",
+		"mixed-content passive mixed content: <img> loads http://example.test/logo.png over http; https://example.test/logo.png is available",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(got, "\n"))
+	}
+	for _, e := range report.Errors {
+		if e.Type == "mixed-content" && (e.Link == nil || e.Link.Line == 0 || e.Source != "https://example.test/start") {
+			t.Fatalf("expected mixed content to point at its element: %+v", e)
+		}
+	}
+}
+
+func TestCrawlReportsTLSCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><p>secure</p></body></html>")
+	}))
+	defer server.Close()
+
+	crawl := func(startURL string, window time.Duration) *Report {
+		t.Helper()
+		client := server.Client()
+		client.Timeout = time.Second
+		report, err := Crawl(context.Background(), Config{
+			StartURL:          startURL,
+			MaxWorkers:        1,
+			Client:            client,
+			Timeout:           time.Second,
+			RequestsPerMinute: 60000,
+			IgnoreRobots:      true,
+			CheckTLS:          true,
+			TLSExpiryWindow:   window,
+		})
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		return report
+	}
+	rules := func(report *Report) string {
+		var got []string
+		for _, f := range report.Findings {
+			if f.Check == CheckTLS {
+				got = append(got, f.Rule)
+			}
+		}
+		return strings.Join(got, " ")
+	}
+
+	report := crawl(server.URL+"/", 0)
+	if len(report.TLS) != 1 {
+		t.Fatalf("expected one TLS host, got %+v", report.TLS)
+	}
+	host := report.TLS[0]
+	if host.Host != strings.TrimPrefix(server.URL, "https://") || host.Version == "" || host.NotAfter.IsZero() || host.Error != "" {
+		t.Fatalf("unexpected TLS host: %+v", host)
+	}
+	if got := rules(report); got != "self-signed" {
+		t.Fatalf("unexpected TLS findings: %q", got)
+	}
+
+	report = crawl(server.URL+"/", 200*365*24*time.Hour)
+	if got := rules(report); got != "certificate-expiring self-signed" {
+		t.Fatalf("expected an expiring certificate: %q", got)
+	}
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	report = crawl("https://localhost:"+port+"/", 0)
+	if len(report.TLS) != 1 || report.TLS[0].Error == "" || !report.TLS[0].HostnameMismatch {
+		t.Fatalf("expected a rejected certificate: %+v", report.TLS)
+	}
+	if got := rules(report); got != "hostname-mismatch self-signed" {
+		t.Fatalf("unexpected TLS findings: %q", got)
+	}
+}
+
+func TestCrawlReportsTLSAlongRedirects(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><p>moved here</p></body></html>")
+	}))
+	defer target.Close()
+	targetHost := strings.TrimPrefix(target.URL, "https://")
+	_, targetPort, _ := net.SplitHostPort(targetHost)
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good":
+			http.Redirect(w, r, target.URL+"/", http.StatusFound)
+		case "/bad":
+			http.Redirect(w, r, "https://localhost:"+targetPort+"/", http.StatusFound)
+		}
+	}))
+	defer origin.Close()
+	originHost := strings.TrimPrefix(origin.URL, "https://")
+
+	crawl := func(path string) map[string]TLSHost {
+		t.Helper()
+		client := origin.Client()
+		client.Timeout = time.Second
+		report, err := Crawl(context.Background(), Config{
+			StartURL:          origin.URL + path,
+			MaxWorkers:        1,
+			Client:            client,
+			Timeout:           time.Second,
+			RequestsPerMinute: 60000,
+			IgnoreRobots:      true,
+			CheckTLS:          true,
+		})
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		hosts := map[string]TLSHost{}
+		for _, h := range report.TLS {
+			hosts[h.Host] = h
+		}
+		return hosts
+	}
+
+	hosts := crawl("/good")
+	if len(hosts) != 2 || hosts[originHost].Version == "" || hosts[targetHost].Version == "" {
+		t.Fatalf("expected both hosts of the redirect chain, got %+v", hosts)
+	}
+
+	hosts = crawl("/bad")
+	if bad := hosts["localhost:"+targetPort]; bad.Error == "" || !bad.HostnameMismatch {
+		t.Fatalf("expected the redirect target to be charged with the failure, got %+v", hosts)
+	}
+	if h := hosts[originHost]; h.Error != "" || h.HostnameMismatch {
+		t.Fatalf("redirecting host was charged with the failure: %+v", h)
+	}
+}
+
+func TestTLSObservationsMerge(t *testing.T) {
+	ok := &TLSHost{Version: "TLS 1.3", Subject: "CN=good"}
+	failed := &TLSHost{Subject: "CN=bad", HostnameMismatch: true, Error: "certificate is not valid"}
+	for _, order := range [][]bool{{true, false}, {false, true}} {
+		c := &crawler{checkTLS: true, tlsHosts: map[string]*tlsObservation{}}
+		u, _ := url.Parse("https://example.test/")
+		for _, success := range order {
+			info := *failed
+			if success {
+				info = *ok
+			}
+			c.recordTLS(u, &info, success)
+		}
+		hosts := c.collectTLS()
+		if len(hosts) != 1 {
+			t.Fatalf("expected one host, got %+v", hosts)
+		}
+		h := hosts[0]
+		if h.Version != "TLS 1.3" || h.Subject != "CN=good" || h.Error == "" || !h.HostnameMismatch {
+			t.Fatalf("order %v: unexpected merged host %+v", order, h)
+		}
+	}
+}
+
+func TestCrawlAuditsSecurityHeaders(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:             "https://example.test/start",
+		MaxWorkers:           2,
+		Client:               &http.Client{Timeout: time.Second, Transport: securityHeaderTransport{}},
+		Timeout:              time.Second,
+		RequestsPerMinute:    60000,
+		MaxDepth:             1,
+		IgnoreRobots:         true,
+		AuditSecurityHeaders: true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	var got []string
+	for _, f := range report.Findings {
+		got = append(got, f.URL+" "+f.Rule)
+	}
+	want := []string{
+		"https://example.test/blog/ content-type-options-missing",
+		"https://example.test/blog/ frame-options-weak",
+		"https://example.test/blog/ referrer-policy-weak",
+		"https://example.test/docs/ cookie-httponly-missing",
+		"https://example.test/docs/ cookie-samesite-missing",
+		"https://example.test/docs/ cookie-secure-missing",
+		"https://example.test/docs/ csp-no-script-policy",
+		"https://example.test/docs/ csp-weak",
+		"https://example.test/docs/ frame-options-missing",
+		"https://example.test/docs/ hsts-missing",
+		"https://example.test/docs/ hsts-weak",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+	for _, f := range report.Findings {
+		if f.Rule == "hsts-weak" && (f.Message != "Strict-Transport-Security max-age is below 180 days on 1 of 2 pages, e.g. https://example.test/docs/b" || f.Snippet != "max-age=300") {
+			t.Fatalf("unexpected hsts-weak finding: %+v", f)
+		}
+	}
+
+	var groups []string
+	for _, g := range report.Headers {
+		groups = append(groups, fmt.Sprintf("%s%s %d %d", g.Host, g.Prefix, g.Pages, len(g.Issues)))
+	}
+	wantGroups := "example.test 4 11\nexample.test/ 1 0\nexample.test/blog 1 3\nexample.test/docs 2 8"
+	if strings.Join(groups, "\n") != wantGroups {
+		t.Fatalf("unexpected header groups:\n%s", strings.Join(groups, "\n"))
+	}
+	if cookies := report.Pages["https://example.test/docs/a"].Headers.Cookies; len(cookies) != 1 || cookies[0].Name != "prefs" || cookies[0].Secure {
+		t.Fatalf("unexpected cookies: %+v", cookies)
+	}
+}
+
+func TestCrawlMeasuresPerformanceBudgets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/start":
+			fmt.Fprint(w, `<a href="/docs/slow">Slow</a><a href="/docs/heavy">Heavy</a>`)
+		case "/docs/slow":
+			time.Sleep(150 * time.Millisecond)
+			fmt.Fprint(w, "<p>slow</p>")
+		case "/docs/heavy":
+			fmt.Fprint(w, "<p>"+strings.Repeat("x", 5000)+"</p>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          server.URL + "/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: 2 * time.Second},
+		Timeout:           2 * time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          1,
+		IgnoreRobots:      true,
+		TTFBBudget:        100 * time.Millisecond,
+		PageWeightBudget:  2000,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	var got []string
+	for _, f := range report.Findings {
+		got = append(got, strings.TrimPrefix(f.URL, server.URL)+" "+f.Rule)
+	}
+	want := "/docs/heavy page-weight-budget\n/docs/slow ttfb-budget"
+	if strings.Join(got, "\n") != want {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+
+	slow := report.Pages[server.URL+"/docs/slow"]
+	if slow.Timing == nil || slow.Timing.TTFB < 150*time.Millisecond || slow.Size != int64(len("<p>slow</p>")) {
+		t.Fatalf("unexpected timing for slow page: %+v %+v", slow.Timing, slow)
+	}
+
+	var groups []string
+	for _, g := range report.Performance {
+		groups = append(groups, fmt.Sprintf("%s %d", g.Prefix, g.Pages))
+	}
+	if strings.Join(groups, ",") != " 3,/ 1,/docs 2" {
+		t.Fatalf("unexpected performance groups: %q", strings.Join(groups, ","))
+	}
+	host := report.Performance[0]
+	if host.TTFB.P99 != slow.Timing.TTFB || host.Size.P99 != 5007 || host.TTFB.P50 > host.TTFB.P90 {
+		t.Fatalf("unexpected host percentiles: %+v", host)
+	}
+}
+
+func TestCrawlCountsWholeBodyOfLargePages(t *testing.T) {
+	body := "<p>" + strings.Repeat("x", int(maxPageSize)) + "</p>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/sized" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/sized", "/streamed"} {
+		report, err := Crawl(context.Background(), Config{
+			StartURL:          server.URL + path,
+			MaxWorkers:        1,
+			Client:            &http.Client{Timeout: 5 * time.Second},
+			Timeout:           5 * time.Second,
+			RequestsPerMinute: 60000,
+			IgnoreRobots:      true,
+		})
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		if page := report.Pages[server.URL+path]; page == nil || page.Size != int64(len(body)) || page.Error != "" {
+			t.Fatalf("%s: expected the whole body to be counted, got %+v", path, page)
+		}
+	}
+}
+
+func TestPercentileUsesNearestRank(t *testing.T) {
+	values := []int64{15, 20, 35, 40, 50}
+	for p, want := range map[float64]int64{5: 15, 30: 20, 40: 20, 50: 35, 100: 50} {
+		if got := percentile(append([]int64(nil), values...), p); got != want {
+			t.Fatalf("percentile %v: got %d, want %d", p, got, want)
+		}
+	}
+	if got := percentile([]time.Duration(nil), 50); got != 0 {
+		t.Fatalf("expected zero for no values, got %v", got)
+	}
+}
+
+func TestCrawlDispatchesOnContentType(t *testing.T) {
+	crawl := func(parseable []string) *Report {
+		t.Helper()
+		report, err := Crawl(context.Background(), Config{
+			StartURL:          "https://example.test/start",
+			MaxWorkers:        2,
+			Client:            &http.Client{Timeout: time.Second, Transport: contentTypeTransport{}},
+			Timeout:           time.Second,
+			MaxDepth:          2,
+			RequestsPerMinute: 60000,
+			IgnoreRobots:      true,
+			AllowedExtensions: []string{"", ".html", ".zip", ".json"},
+			MarkdownDir:       t.TempDir(),
+			ParseableTypes:    parseable,
+		})
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		return report
+	}
+
+	report := crawl(nil)
+	var got []string
+	for _, e := range report.Errors {
+		got = append(got, e.Type+" "+e.Target+" "+e.Message)
+	}
+	sort.Strings(got)
+	want := []string{
+		"content-type https://example.test/api no valid Content-Type header; content sniffed as text/plain",
+		"content-type https://example.test/page.html served as text/plain, but the extension suggests text/html",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(got, "\n"))
+	}
+	archive := report.Pages["https://example.test/files.zip"]
+	if archive == nil || archive.ContentType != "application/zip" || len(archive.Links) != 0 || archive.MarkdownPath != "" || archive.MarkdownSkippedReason != "content type application/zip" {
+		t.Fatalf("expected the archive to be status-checked only: %+v", archive)
+	}
+	for _, hidden := range []string{"https://example.test/from-zip", "https://example.test/from-text"} {
+		if _, ok := report.Pages[hidden]; ok {
+			t.Fatalf("did not expect %s to be crawled", hidden)
+		}
+	}
+
+	report = crawl([]string{"text/html", "text/plain; charset=utf-8"})
+	if _, ok := report.Pages["https://example.test/from-text"]; !ok {
+		t.Fatalf("expected text/plain bodies to be parsed when configured")
+	}
+}
+
+func TestCrawlFollowsPDFLinkAnnotations(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		AllowExternal:     true,
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: pdfTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          2,
+		IgnoreRobots:      true,
+		AllowedExtensions: []string{"", ".html", ".pdf"},
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	var got []string
+	for _, e := range report.Errors {
+		switch {
+		case e.Source == e.Target:
+			// Internal pages are reported against themselves; the links to
+			// them are listed in Referrers.
+			if e.Link != nil {
+				t.Fatalf("expected page error without a link: %+v", e)
+			}
+			refs := report.Referrers[e.Target]
+			if len(refs) != 1 {
+				t.Fatalf("expected one referrer for %s, got %+v", e.Target, refs)
+			}
+			got = append(got, fmt.Sprintf("%s %s from %s %q", e.Type, e.Target, refs[0].Source, refs[0].Text))
+		case e.Link == nil:
+			t.Fatalf("expected error to carry its link: %+v", e)
+		default:
+			got = append(got, fmt.Sprintf("%s %s page %d %q", e.Type, e.Target, e.Link.Page, e.Link.Text))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		`http https://example.test/docs/missing from https://example.test/manual.pdf "Missing (old) page"`,
+		`http https://ext.test/guide page 2 ""`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(got, "\n"))
+	}
+	if refs := report.Referrers["https://ext.test/guide"]; len(refs) != 1 || refs[0].Source != "https://example.test/manual.pdf" {
+		t.Fatalf("expected the PDF as referrer: %+v", refs)
+	}
+
+	if _, err := (&crawler{}).extractPDFLinks([]byte("%PDF-1.4\n1 0 obj << /Encrypt 2 0 R >> endobj"), "https://example.test/a.pdf"); err != errPDFEncrypted {
+		t.Fatalf("expected encrypted PDFs to be rejected, got %v", err)
+	}
+}
+
+func TestCrawlReportsTruncatedPDFs(t *testing.T) {
+	defer func(size int64) { maxPDFSize = size }(maxPDFSize)
+	maxPDFSize = 64
+
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: pdfTransport{}},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		MaxDepth:          2,
+		IgnoreRobots:      true,
+		AllowedExtensions: []string{"", ".html", ".pdf"},
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	var truncated bool
+	for _, e := range report.Errors {
+		if e.Type == "pdf" && e.Target == "https://example.test/manual.pdf" && strings.Contains(e.Message, "larger than 64 bytes") {
+			truncated = true
+		}
+	}
+	if !truncated {
+		t.Fatalf("expected a pdf error for the truncated document: %+v", report.Errors)
+	}
+}
+
+func TestCrawlChecksStylesheetReferences(t *testing.T) {
+	transport := &cssTransport{}
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/start",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: transport},
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		IgnoreRobots:      true,
+		CheckCSS:          true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	var got []string
+	for _, e := range report.Errors {
+		got = append(got, fmt.Sprintf("%s -> %s %s line %d", strings.TrimPrefix(e.Source, "https://example.test"), strings.TrimPrefix(e.Target, "https://example.test"), e.Link.Element, e.Link.Line))
+	}
+	sort.Strings(got)
+	want := []string{
+		"/css/base.css -> /css/missing-from-base.png css-url line 2",
+		"/css/site.css -> /fonts/missing.woff2 css-url line 4",
+		"/start -> /css/gone.css stylesheet line 3",
+		"/start -> /css/truncated.css stylesheet line 5",
+		"/start -> /img/missing-inline.png css-url line 4",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(got, "\n"))
+	}
+	for _, e := range report.Errors {
+		if strings.HasSuffix(e.Target, "/css/truncated.css") && e.Type != "read" {
+			t.Fatalf("expected a read error for the truncated stylesheet, got %+v", e)
+		}
+	}
+	for _, path := range []string{"example.test/img/commented.png", "example.test/img/commented-style.png", "example.test/img/scripted.png", "cdn.test/bg.png"} {
+		if transport.requested(path) {
+			t.Fatalf("did not expect a request for %s", path)
+		}
+	}
+	if !transport.requested("example.test/img/ok.png") || !transport.requested("example.test/img/hero.png") {
+		t.Fatalf("expected referenced images to be checked")
+	}
+	if report.Stats.ExternalLinksChecked != 0 {
+		t.Fatalf("internal assets must not count as external links: %+v", report.Stats)
+	}
+}
+
+func TestCrawlChecksLinkRelations(t *testing.T) {
+	report, err := Crawl(context.Background(), Config{
+		StartURL:          "https://example.test/en",
+		MaxWorkers:        2,
+		Client:            &http.Client{Timeout: time.Second, Transport: relationsTransport{}},
+		MaxDepth:          1,
+		Timeout:           time.Second,
+		RequestsPerMinute: 60000,
+		IgnoreRobots:      true,
+		CheckRelations:    true,
+	})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	var got []string
+	for _, e := range report.Errors {
+		if e.Link == nil {
+			got = append(got, fmt.Sprintf("%s -> %s %s", strings.TrimPrefix(e.Source, "https://example.test"), strings.TrimPrefix(e.Target, "https://example.test"), e.Message))
+			continue
+		}
+		got = append(got, fmt.Sprintf("%s -> %s %s %v line %d", strings.TrimPrefix(e.Source, "https://example.test"), strings.TrimPrefix(e.Target, "https://example.test"), e.Link.Element, e.Link.Rel, e.Link.Line))
+	}
+	sort.Strings(got)
+	want := []string{
+		"/amp/en -> /amp/en status 404",
+		"/en -> /img/missing.png srcset [] line 8",
+		"/en/page-2 -> /en/page-2 status 404",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(got, "\n"))
+	}
+	if refs := report.Referrers["https://example.test/en/page-2"]; len(refs) != 1 || refs[0].Source != "https://example.test/en" || refs[0].Line != 6 {
+		t.Fatalf("expected the relation to be listed as referrer, got %+v", refs)
+	}
+
+	relations := report.Pages["https://example.test/en"].Relations
+	if relations == nil || relations.Canonical != "https://example.test/en" || relations.Next != "https://example.test/en/page-2" || len(relations.Alternates) != 4 {
+		t.Fatalf("unexpected relations: %+v", relations)
+	}
+	if want := []string{"https://example.test/img/a.png", "https://example.test/img/missing.png"}; !slices.Equal(relations.Srcset, want) {
+		t.Fatalf("unexpected srcset candidates: %v", relations.Srcset)
+	}
+	if _, ok := report.Pages["https://example.test/fr"]; !ok {
+		t.Fatalf("expected alternate pages to be crawled")
+	}
+
+	got = nil
+	for _, f := range report.Findings {
+		got = append(got, fmt.Sprintf("%s %s %s line %d", strings.TrimPrefix(f.URL, "https://example.test"), f.Rule, strings.TrimPrefix(f.Snippet, "https://example.test"), f.Line))
+	}
+	want = []string{
+		"/en hreflang-invalid /en line 5",
+		"/en hreflang-not-reciprocal /fr line 4",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestCrawlRespectsRobotsDirectives(t *testing.T) {
+	crawl := func(cfg Config) *Report {
+		t.Helper()
+		cfg.StartURL = "https://example.test/start"
+		cfg.MaxWorkers = 2
+		cfg.MaxDepth = 2
+		cfg.Client = &http.Client{Timeout: time.Second, Transport: metaRobotsTransport{}}
+		cfg.Timeout = time.Second
+		cfg.RequestsPerMinute = 60000
+		report, err := Crawl(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("crawl failed: %v", err)
+		}
+		return report
+	}
+
+	report := crawl(Config{SkipNofollowLinks: true})
+	var got []string
+	for _, skip := range report.Skipped {
+		got = append(got, strings.TrimPrefix(skip.URL, "https://example.test")+" "+string(skip.Reason))
+	}
+	want := []string{"/b rel-nofollow", "/hidden-1 nofollow", "/hidden-2 nofollow"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected skips: %v", got)
+	}
+	if report.Stats.SkippedByNofollow != 2 || report.Stats.SkippedByRelNofollow != 1 {
+		t.Fatalf("unexpected skip counters: %+v", report.Stats)
+	}
+	if _, ok := report.Pages["https://example.test/c"]; !ok {
+		t.Fatalf("directives for other user agents must be ignored")
+	}
+	if want := []string{"https://example.test/meta-nofollow"}; !slices.Equal(report.NoIndex, want) {
+		t.Fatalf("unexpected noindex pages: %v", report.NoIndex)
+	}
+	if page := report.Pages["https://example.test/header-nofollow"]; !page.NoFollow || page.NoIndex {
+		t.Fatalf("unexpected directives for header page: %+v", page)
+	}
+
+	report = crawl(Config{CheckRelations: true, CheckCSS: true})
+	got = nil
+	for _, skip := range report.Skipped {
+		if skip.Reason == SkipReasonNofollow {
+			got = append(got, strings.TrimPrefix(skip.URL, "https://example.test"))
+		}
+	}
+	if want := []string{"/canonical", "/hidden-1", "/hidden-2", "/img.png", "/site.css"}; !slices.Equal(got, want) {
+		t.Fatalf("relations and stylesheets of nofollow pages must be withheld, got %v", got)
+	}
+
+	report = crawl(Config{IgnoreMetaRobots: true})
+	for _, path := range []string{"/b", "/hidden-1", "/hidden-2"} {
+		if _, ok := report.Pages["https://example.test"+path]; !ok {
+			t.Fatalf("expected %s to be crawled when directives are ignored", path)
+		}
+	}
+	if len(report.Skipped) != 0 || len(report.NoIndex) != 1 {
+		t.Fatalf("unexpected skips %v or noindex pages %v", report.Skipped, report.NoIndex)
+	}
+}
+
+func TestParseSrcsetFollowsCandidateSyntax(t *testing.T) {
+	got := parseSrcset(" a.png 1x,b.png  2x ,\nc,d.png, image(1,2).png 100w, data:image/png;base64,AAA 3x,e.png,, ")
+	want := []string{"a.png", "b.png", "c,d.png", "image(1,2).png", "e.png"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected candidates: %q", got)
+	}
+}
+
+func TestExtensionMediaTypeCoversStaticFilesOnly(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://example.test/page.HTML":   "text/html",
+		"https://example.test/app.js?v=2":  "text/javascript",
+		"https://example.test/index.php":   "",
+		"https://example.test/default.asp": "",
+		"https://example.test/docs/":       "",
+	} {
+		if got := extensionMediaType(rawURL); got != want {
+			t.Errorf("%s: got %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestParsePDFRejectsMalformedOffsets(t *testing.T) {
+	for name, body := range map[string]string{
+		"stream length overflow": "%PDF-1.7\n1 0 obj\n<< /Length 9223372036854775800 >>\nstream\nabc\nendstream\nendobj\n",
+		"negative object offset": "%PDF-1.7\n1 0 obj\n<< /Type /ObjStm /N 1 /First 6 /Length 10 >>\nstream\n5 -100<<>>\nendstream\nendobj\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parsePDF([]byte(body)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePDFRejectsDeepNesting(t *testing.T) {
+	nested := "%PDF-1.7\n1 0 obj\n" + strings.Repeat("[", 1<<20) + "\nendobj\n2 0 obj\n<< /Type /Catalog >>\nendobj\n"
+	doc, err := parsePDF([]byte(nested))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := doc.objects[1]; ok {
+		t.Fatal("expected the deeply nested object to be skipped")
+	}
+	if _, ok := doc.objects[2]; !ok {
+		t.Fatal("expected the objects after it to be parsed")
+	}
+
+	p := &pdfParser{data: []byte(strings.Repeat("<< /A ", maxPDFNesting) + "1" + strings.Repeat(" >>", maxPDFNesting))}
+	if _, err := p.value(); err != nil {
+		t.Fatalf("expected nesting up to the limit to parse: %v", err)
+	}
+}
+
+func TestTLSFindingsFlagOldProtocols(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	findings := tlsFindings([]TLSHost{
+		{Host: "old.test", Version: "TLS 1.0", VersionTooOld: true, NotAfter: now.Add(-time.Hour)},
+		{Host: "new.test", Version: "TLS 1.3", NotAfter: now.Add(365 * 24 * time.Hour)},
+	}, now, defaultTLSExpiryWindow)
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.URL+" "+f.Rule+" "+string(f.Severity))
+	}
+	want := "https://old.test/ certificate-expired error\nhttps://old.test/ protocol-version warning"
+	if strings.Join(got, "\n") != want {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func appendToFile(path, data string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	return err
+}
+
+type fakeTransport struct {
+	linkCount int
+}
+
+// slowTransport delays every detail page until the delay elapses or the
+// request context is done.
+type slowTransport struct {
+	*fakeTransport
+	delay time.Duration
+}
+
+type emptyContentTransport struct{}
+
+// cancelTransport serves a single page without links and cancels the crawl
+// once it has answered.
+type cancelTransport struct {
+	cancel context.CancelFunc
+}
+
+// flakyTransport answers the first failures requests with 503.
+type flakyTransport struct {
+	failures int64
+	calls    atomic.Int64
+}
+
+// referrerTransport serves two pages that both link to the same missing
+// internal page and the same dead external URL.
+type referrerTransport struct{}
+
+// soft404Transport answers unknown paths with 200 and a "not found" template
+// that echoes the requested path and carries a per-request reference. Its
+// robots.txt disallows the soft-404 probe.
+type soft404Transport struct{}
+
+// duplicateTransport serves two identical pages and two long articles that
+// differ in a single word.
+type duplicateTransport struct{}
+
+// layoutTransport serves short, distinct articles wrapped in the same site
+// header and footer.
+type layoutTransport struct{}
+
+// seoTransport serves pages with complete, duplicate and broken metadata.
+// The missing canonical target of /a is not linked from anywhere.
+type seoTransport struct{}
+
+// mixedContentTransport serves an https page that loads http resources and
+// only offers https for its own host.
+type mixedContentTransport struct{}
+
+// cssTransport serves a page with stylesheets, style blocks and style
+// attributes, and records the host and path of every request.
+type cssTransport struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// relationsTransport serves three language versions of a page, of which the
+// French one does not link back to the English one, and pagination, AMP and
+// srcset targets that are partly missing.
+type relationsTransport struct{}
+
+// metaRobotsTransport serves pages carrying robots directives in meta tags
+// and X-Robots-Tag headers, one of them addressed to another crawler.
+type metaRobotsTransport struct{}
+
+// pdfTransport serves a page linking to a PDF whose link annotations point
+// to missing pages.
+type pdfTransport struct{}
+
+// contentTypeTransport serves documents of several media types, some of them
+// mislabelled.
+type contentTypeTransport struct{}
+
+// securityHeaderTransport serves pages whose response headers range from
+// complete to missing or weak.
+type securityHeaderTransport struct{}
+
+// structureTransport serves a small site where /old redirects to /new and
+// /orphan is only reachable through the sitemap.
+type structureTransport struct{}
+
+type depthTransport struct {
+	maxLevel int
+}
+
+func (ft *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "example.test" {
+		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+	}
+
+	switch req.URL.Path {
+	case "/robots.txt":
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	case "/start":
+		markup := buildListingPageHTML(ft.linkCount)
+		return newStringResponse(req, http.StatusOK, markup), nil
+	case "/meta-redirect":
+		markup := "<!doctype html><html><head><title>Redirecting</title><meta http-equiv=\"refresh\" content=\"0; url=/page/0\"></head><body><p>Redirecting...</p></body></html>"
+		return newStringResponse(req, http.StatusOK, markup), nil
+	default:
+		if strings.HasPrefix(req.URL.Path, "/page/") {
+			pageID := strings.TrimPrefix(req.URL.Path, "/page/")
+			markup := buildDetailPageHTML(pageID)
+			return newStringResponse(req, http.StatusOK, markup), nil
+		}
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (st slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/page/") {
+		select {
+		case <-time.After(st.delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return st.fakeTransport.RoundTrip(req)
+}
+
+func (ct cancelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer ct.cancel()
+	return newStringResponse(req, http.StatusOK, "<title>Only page</title><p>Nothing to follow.</p>"), nil
+}
+
+func (emptyContentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "example.test" {
+		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+	}
+
+	switch req.URL.Path {
+	case "/robots.txt":
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	case "/start":
+		markup := "<!doctype html><html><head><title>Empty Page Title</title><meta name=\"description\" content=\"This description summarises the page.\"></head><body></body></html>"
+		return newStringResponse(req, http.StatusOK, markup), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (ft *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ft.calls.Add(1) <= ft.failures {
+		return newStringResponse(req, http.StatusServiceUnavailable, ""), nil
+	}
+	return newStringResponse(req, http.StatusOK, "<p>ok</p>"), nil
+}
+
+func (referrerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "dead.test" {
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<a href="/a">A</a><a href="/b">B</a>`), nil
+	case "/a":
+		return newStringResponse(req, http.StatusOK, "<html>\n<body>\n<p><a href=\"https://dead.test/sdk\">Download <b>the</b>\n SDK</a></p><a href=\"/missing\">Missing</a>\n</body></html>"), nil
+	case "/b":
+		return newStringResponse(req, http.StatusOK, "<ul>\n<li><a href='/missing'>Gone</a> <a href=https://dead.test/sdk>SDK</a></li></ul>"), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (soft404Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case "/robots.txt":
+		return newStringResponse(req, http.StatusOK, "User-agent: *\nDisallow: /linkcheck-\n"), nil
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<title>Home</title><a href="/a">A</a> <a href="/gone">Gone</a> <a href="/retired">Retired</a>`), nil
+	case "/a":
+		return newStringResponse(req, http.StatusOK, `<title>A</title><p>Real content.</p>`), nil
+	case "/retired":
+		return newStringResponse(req, http.StatusOK, `<title>Retired</title><p>This product is no longer available.</p>`), nil
+	default:
+		words := make([]string, 0, 2000)
+		for i := 0; i < 2000; i++ {
+			words = append(words, "suggestion"+strconv.Itoa(i))
+		}
+		// The reference differs per request, so only a close match finds it.
+		words[1000] = "ref" + strconv.Itoa(len(req.URL.Path))
+		return newStringResponse(req, http.StatusOK, "<title>Page not found</title><p>Sorry, we could not find "+req.URL.Path+".</p><p>"+strings.Join(words, " ")+"</p>"), nil
+	}
+}
+
+func (duplicateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	article := func(changed string) string {
+		words := make([]string, 0, 1200)
+		for i := 0; i < 1200; i++ {
+			words = append(words, "term"+strconv.Itoa(i))
+		}
+		words[600] = changed
+		return "<article><p>" + strings.Join(words, " ") + "</p></article>"
+	}
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<a href="/copy-a">A</a> <a href="/copy-b">B</a> <a href="/draft">Draft</a> <a href="/final">Final</a>`), nil
+	case "/copy-a", "/copy-b":
+		return newStringResponse(req, http.StatusOK, "<p>The same product description on two URLs.</p>"), nil
+	case "/draft":
+		return newStringResponse(req, http.StatusOK, article("draft")), nil
+	case "/final":
+		return newStringResponse(req, http.StatusOK, article("final")), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (layoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	layout := func(content string) string {
+		return `<header><ul><li><a href="/">Home</a></li><li><a href="/products">Products</a></li><li><a href="/pricing">Pricing</a></li><li><a href="/support">Support</a></li><li><a href="/blog">Blog</a></li><li><a href="/contact">Contact us</a></li></ul></header>` +
+			"<main>" + content + "</main>" +
+			`<footer><ul><li>Copyright Example Corporation, all rights reserved</li><li><a href="/imprint">Imprint and legal notice</a></li><li><a href="/privacy">Privacy policy and cookie settings</a></li><li><a href="/terms">Terms of service</a></li><li><a href="/jobs">We are hiring engineers</a></li><li><a href="/status">Service status</a></li></ul></footer>`
+	}
+	topics := []string{"Apples grow on trees.", "Rivers flow to the sea.", "Cats chase small mice.", "Trains run on rails.", "Bees make sweet honey.", "Snow falls in winter.", "Stars shine at night."}
+	if req.URL.Path == "/start" {
+		var links strings.Builder
+		for i := range topics {
+			fmt.Fprintf(&links, `<p><a href="/article-%d">Article %d</a></p>`, i, i)
+		}
+		return newStringResponse(req, http.StatusOK, layout(links.String())), nil
+	}
+	var i int
+	if _, err := fmt.Sscanf(req.URL.Path, "/article-%d", &i); err == nil && i >= 0 && i < len(topics) {
+		return newStringResponse(req, http.StatusOK, layout("<p>"+topics[i]+"</p>")), nil
+	}
+	return newStringResponse(req, http.StatusNotFound, ""), nil
+}
+
+func (seoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	longTitle := strings.Repeat("Very long title ", 5)
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<html lang="en"><head><title>Home</title>
+<meta name="description" content="Welcome to the example site.">
+<meta property="og:title" content="Example">
+<!-- <link rel="canonical" href="/old"> <meta name="description" content="Old"> -->
+<link rel="canonical" href="/start"></head>
+<body><h1>Home</h1><script>document.write("<h1>Injected</h1>")</script>
+<a href="/a">A</a> <a href="/b">B</a></body></html>`), nil
+	case "/a":
+		return newStringResponse(req, http.StatusOK, `<title>`+longTitle+`</title><link rel="canonical" href="/missing"><h1>One</h1><h1>Two</h1>`), nil
+	case "/b":
+		return newStringResponse(req, http.StatusOK, `<title>`+longTitle+`</title>`), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (securityHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "<p>page</p>"
+	if req.URL.Path == "/start" {
+		body = `<a href="/docs/a">A</a><a href="/docs/b">B</a><a href="/blog/x">X</a>`
+	}
+	resp := newStringResponse(req, http.StatusOK, body)
+	h := resp.Header
+	h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	h.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+	switch req.URL.Path {
+	case "/start":
+		h.Set("Set-Cookie", "session=1; Secure; HttpOnly; SameSite=Lax")
+	case "/docs/a":
+		h.Del("Strict-Transport-Security")
+		h.Set("Content-Security-Policy", "frame-ancestors 'none'")
+		h.Set("Set-Cookie", "prefs=x")
+	case "/docs/b":
+		h.Set("Strict-Transport-Security", "max-age=300")
+		h.Set("Content-Security-Policy", "script-src 'self' 'unsafe-inline'")
+	case "/blog/x":
+		h.Set("Content-Security-Policy", "default-src 'self'")
+		h.Set("X-Frame-Options", "ALLOW-FROM https://a.test")
+		h.Set("Referrer-Policy", "unsafe-url")
+		h.Del("X-Content-Type-Options")
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+	return resp, nil
+}
+
+func (contentTypeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	contentType, body := "text/html; charset=utf-8", "<p>page</p>"
+	switch req.URL.Path {
+	case "/start":
+		body = `<a href="/files.zip">Files</a><a href="/page.html">Page</a><a href="/api">API</a><a href="/data.json">Data</a>`
+	case "/files.zip":
+		contentType, body = "application/zip", `PK <a href="/from-zip">x</a>`
+	case "/page.html":
+		contentType, body = "text/plain; charset=utf-8", `<a href="/from-text">x</a>`
+	case "/api":
+		contentType, body = "", `{"ok": true}`
+	case "/data.json":
+		contentType, body = "application/json", `{"ok": true}`
+	case "/from-text":
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+	resp := newStringResponse(req, http.StatusOK, body)
+	resp.Header.Set("Content-Type", contentType)
+	if contentType == "" {
+		resp.Header.Del("Content-Type")
+	}
+	return resp, nil
+}
+
+func (pdfTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Host + req.URL.Path {
+	case "example.test/start":
+		return newStringResponse(req, http.StatusOK, `<a href="/manual.pdf">Manual</a>`), nil
+	case "example.test/manual.pdf":
+		resp := newStringResponse(req, http.StatusOK, string(buildTestPDF()))
+		resp.Header.Set("Content-Type", "application/pdf")
+		return resp, nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+// buildTestPDF returns a two-page PDF. The first page links to a relative URL
+// from a plain object; the link annotations of the second page live in a
+// compressed object stream and use a hex-encoded URI.
+func buildTestPDF() []byte {
+	var packed bytes.Buffer
+	zw := zlib.NewWriter(&packed)
+	objects := []string{
+		"[8 0 R 9 0 R]",
+		"<< /Subtype /Link /A 10 0 R >>",
+		"<< /Subtype /Link /Dest [3 0 R /Fit] >>",
+		"<< /S /URI /URI <" + hex.EncodeToString([]byte("https://ext.test/guide")) + "> >>",
+	}
+	var header, data strings.Builder
+	for i, obj := range objects {
+		fmt.Fprintf(&header, "%d %d ", []int{6, 8, 9, 10}[i], data.Len())
+		data.WriteString(obj + "\n")
+	}
+	zw.Write([]byte(header.String() + data.String()))
+	zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	for _, obj := range []string{
+		"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n",
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>\nendobj\n",
+		"3 0 obj\n<< /Type /Page /Parent 2 0 R /Annots [5 0 R] >>\nendobj\n",
+		"4 0 obj\n<< /Type /Page /Parent 2 0 R /Annots 6 0 R >>\nendobj\n",
+		"5 0 obj\n<< /Type /Annot /Subtype /Link /Contents (Missing \\(old\\) page) /A << /S /URI /URI (/docs/missing) >> >>\nendobj\n",
+		fmt.Sprintf("7 0 obj\n<< /Type /ObjStm /N 4 /First %d /Filter /FlateDecode /Length %d >>\nstream\n", len(header.String()), packed.Len()),
+	} {
+		pdf.WriteString(obj)
+	}
+	pdf.Write(packed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R /Size 11 >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func (metaRobotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<html><body>
+<a href="/a">a</a> <a href="/b" rel="nofollow">b</a>
+<a href="/meta-nofollow">meta</a> <a href="/header-nofollow">header</a>
+<a href="/other-agent">other</a></body></html>`), nil
+	case "/meta-nofollow":
+		return newStringResponse(req, http.StatusOK, `<html><head><meta name="robots" content="noindex, nofollow">
+<link rel="canonical" href="/canonical"><link rel="stylesheet" href="/site.css"></head>
+<body><a href="/hidden-1">hidden</a><img srcset="/img.png 2x"></body></html>`), nil
+	case "/header-nofollow":
+		resp := newStringResponse(req, http.StatusOK, `<html><body><a href="/hidden-2">hidden</a></body></html>`)
+		resp.Header.Add("X-Robots-Tag", "noarchive")
+		resp.Header.Add("X-Robots-Tag", "linkcheck-bot: nofollow")
+		return resp, nil
+	case "/other-agent":
+		resp := newStringResponse(req, http.StatusOK, `<html><body><a href="/c">c</a></body></html>`)
+		resp.Header.Set("X-Robots-Tag", "googlebot: noindex, nofollow")
+		return resp, nil
+	case "/a", "/b", "/c", "/hidden-1", "/hidden-2":
+		return newStringResponse(req, http.StatusOK, `<html><body>ok</body></html>`), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (relationsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "example.test" {
+		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+	}
+	switch req.URL.Path {
+	case "/en":
+		return newStringResponse(req, http.StatusOK, `<html><head>
+<link rel="canonical" href="/en">
+<link rel="alternate" hreflang="de" href="/de">
+<link rel="alternate" hreflang="fr" href="https://example.test/fr#top">
+<link rel="alternate" hreflang="english" href="/en">
+<link rel="next" href="/en/page-2"><link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="amphtml" href="/amp/en">
+</head><body><img src="/img/a.png" srcset="/img/a.png 1x, /img/missing.png 2x">
+<link rel="alternate" hreflang="x-default" href="/en"></body></html>`), nil
+	case "/de":
+		return newStringResponse(req, http.StatusOK, `<html><head>
+<link rel="alternate" hreflang="en" href="/en">
+<link rel="alternate" hreflang="de" href="/de">
+</head><body></body></html>`), nil
+	case "/fr":
+		return newStringResponse(req, http.StatusOK, `<html><head>
+<link rel="alternate" hreflang="fr" href="/fr">
+</head><body></body></html>`), nil
+	case "/img/a.png":
+		return newStringResponse(req, http.StatusOK, ""), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (ct *cssTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.mu.Lock()
+	ct.paths = append(ct.paths, req.URL.Host+req.URL.Path)
+	ct.mu.Unlock()
+	if req.URL.Host != "example.test" {
+		return nil, fmt.Errorf("unexpected host: %s", req.URL.Host)
+	}
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<html><head>
+<link rel="stylesheet" href="/css/site.css">
+<link rel="stylesheet" href="/css/gone.css">
+<style>.a { background: url('/img/missing-inline.png') }</style>
+<link rel="stylesheet" href="/css/truncated.css">
+<!-- <style>.z { background: url(/img/commented-style.png) }</style> -->
+<script>var s = "<style>.q { background: url(/img/scripted.png) }</style>";</script>
+</head><body><div style="background-image: url(&quot;/img/ok.png&quot;)">x</div></body></html>`), nil
+	case "/css/site.css":
+		return newStringResponse(req, http.StatusOK, `@import "base.css";
+/* .old { background: url(/img/commented.png) } */
+.hero { background: url(../img/hero.png) }
+@font-face { src: url("/fonts/missing.woff2") format("woff2"), url(data:font/woff;base64,AAA) }
+.x { filter: url(#f) }`), nil
+	case "/css/base.css":
+		return newStringResponse(req, http.StatusOK, `body { background: url(https://cdn.test/bg.png) }
+.y { background: url(missing-from-base.png) }`), nil
+	case "/css/truncated.css":
+		resp := newStringResponse(req, http.StatusOK, "")
+		resp.Body = io.NopCloser(io.MultiReader(strings.NewReader(".t { color: red }"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+		return resp, nil
+	case "/img/ok.png", "/img/hero.png":
+		return newStringResponse(req, http.StatusOK, ""), nil
+	default:
+		return newStringResponse(req, http.StatusNotFound, ""), nil
+	}
+}
+
+func (ct *cssTransport) requested(path string) bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return slices.Contains(ct.paths, path)
+}
+
+func (mixedContentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "example.test" {
+		return nil, fmt.Errorf("connection refused")
+	}
+	if req.URL.Path == "/start" {
+		return newStringResponse(req, http.StatusOK, `<html lang="en">
+<script src="http://cdn.test/app.js"></script>
+<!-- <script src="http://cdn.test/old.js"></script> -->
+<img src="http://example.test/logo.png" alt="Logo">
+<img src="/secure.png" alt="">
+<a href="http://example.test/about">About</a>
+</html>`), nil
+	}
+	return newStringResponse(req, http.StatusOK, ""), nil
+}
+
+func (structureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case "/start":
+		return newStringResponse(req, http.StatusOK, `<a href="/a">A</a><a href="/old">Old</a>`), nil
+	case "/a":
+		return newStringResponse(req, http.StatusOK, `<a href="/start">Home</a><a href="/old">Old</a>`), nil
+	case "/old":
+		resp := newStringResponse(req, http.StatusMovedPermanently, "")
+		resp.Header.Set("Location", "HTTPS://Example.test/new#intro")
+		return resp, nil
+	case "/new":
+		return newStringResponse(req, http.StatusOK, "<p>No links here.</p>"), nil
+	case "/orphan":
+		return newStringResponse(req, http.StatusOK, `<a href="/a">A</a>`), nil
 	default:
 		return newStringResponse(req, http.StatusNotFound, ""), nil
 	}
@@ -484,7 +2225,7 @@ func newStringResponse(req *http.Request, status int, body string) *http.Respons
 	return &http.Response{
 		StatusCode: status,
 		Body:       io.NopCloser(strings.NewReader(body)),
-		Header:     make(http.Header),
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
 		Request:    req,
 	}
 }
@@ -0,0 +1,247 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxCSSImportDepth bounds chains of @import rules between stylesheets.
+const maxCSSImportDepth = 8
+
+var (
+	cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssImportPattern  = regexp.MustCompile(`(?i)@import\s+(?:"([^"]*)"|'([^']*)')`)
+	cssURLPattern     = regexp.MustCompile(`(?i)(@import\s+)?\burl\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`)
+	styleBlockPattern = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style\s*>`)
+)
+
+// cssReference is a URL referenced by a stylesheet. offset is the position of
+// the reference in the stylesheet; imported marks @import rules.
+type cssReference struct {
+	raw      string
+	offset   int
+	imported bool
+}
+
+// parseCSSReferences returns the url() and @import references of a
+// stylesheet in document order. Comments are ignored, and so are data URLs
+// and fragment-only references such as url(#filter).
+func parseCSSReferences(css []byte) []cssReference {
+	masked := cssCommentPattern.ReplaceAllFunc(css, func(comment []byte) []byte {
+		blank := make([]byte, len(comment))
+		for i, c := range comment {
+			blank[i] = ' '
+			if c == '\n' {
+				blank[i] = '\n'
+			}
+		}
+		return blank
+	})
+
+	var refs []cssReference
+	add := func(raw string, offset int, imported bool) {
+		raw = strings.TrimSpace(raw)
+		lower := strings.ToLower(raw)
+		if raw == "" || strings.HasPrefix(raw, "#") || strings.HasPrefix(lower, "data:") || strings.HasPrefix(lower, "about:") || strings.HasPrefix(lower, "javascript:") {
+			return
+		}
+		refs = append(refs, cssReference{raw: raw, offset: offset, imported: imported})
+	}
+	for _, m := range cssImportPattern.FindAllSubmatchIndex(masked, -1) {
+		switch {
+		case m[2] >= 0:
+			add(string(masked[m[2]:m[3]]), m[0], true)
+		case m[4] >= 0:
+			add(string(masked[m[4]:m[5]]), m[0], true)
+		}
+	}
+	for _, m := range cssURLPattern.FindAllSubmatchIndex(masked, -1) {
+		imported := m[2] >= 0
+		for group := 4; group <= 8; group += 2 {
+			if m[group] >= 0 {
+				add(string(masked[m[group]:m[group+1]]), m[0], imported)
+				break
+			}
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].offset < refs[j].offset })
+	return refs
+}
+
+// checkCSS validates the resources referenced by the stylesheets of a page:
+// same-site stylesheets linked from it are fetched and their url() and
+// @import references checked with the stylesheet as source, while references
+// in <style> blocks and style attributes are checked with the page as source.
+// References withheld by nofollow directives of page are not fetched.
+func (c *crawler) checkCSS(ctx context.Context, page *PageReport, pageURL string, body []byte) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	lines := newLineIndex(body)
+	masked := maskHiddenMarkup(body)
+
+	// Masking blanks the contents of <style> blocks but keeps their tags and
+	// offsets, so the blocks are located in the masked page, which skips
+	// those in comments and scripts, and read from the original.
+	for _, m := range styleBlockPattern.FindAllSubmatchIndex(masked, -1) {
+		for _, ref := range parseCSSReferences(body[m[2]:m[3]]) {
+			c.checkCSSReference(ctx, page, pageURL, base, ref, lines.line(m[2]+ref.offset), 0)
+		}
+	}
+
+	for _, m := range startTagPattern.FindAllSubmatchIndex(masked, -1) {
+		name := strings.ToLower(string(masked[m[2]:m[3]]))
+		attrs := parseTagAttributes(masked[m[4]:m[5]])
+		if style := attrs["style"]; style != "" {
+			for _, ref := range parseCSSReferences([]byte(style)) {
+				c.checkCSSReference(ctx, page, pageURL, base, ref, lines.line(m[0]), 0)
+			}
+		}
+		if name != "link" || !hasToken(attrs["rel"], "stylesheet") || attrs["href"] == "" {
+			continue
+		}
+		ref, err := url.Parse(attrs["href"])
+		if err != nil {
+			continue
+		}
+		link := c.cssLink(base.ResolveReference(ref), LinkElementStylesheet, lines.line(m[0]))
+		if link == nil || c.withheldLink(page, *link) {
+			continue
+		}
+		if link.Type == LinkTypeInternal {
+			c.checkStylesheet(ctx, pageURL, *link, 0)
+		} else {
+			c.checkAsset(ctx, pageURL, *link)
+		}
+	}
+}
+
+// checkCSSReference validates one reference found in CSS served from base.
+// Same-site stylesheets pulled in by @import are fetched in turn. page is set
+// for references made by a page itself, whose nofollow directives apply.
+func (c *crawler) checkCSSReference(ctx context.Context, page *PageReport, source string, base *url.URL, ref cssReference, line, depth int) {
+	parsed, err := url.Parse(ref.raw)
+	if err != nil {
+		return
+	}
+	element := LinkElementCSSURL
+	if ref.imported {
+		element = LinkElementCSSImport
+	}
+	link := c.cssLink(base.ResolveReference(parsed), element, line)
+	if link == nil || page != nil && c.withheldLink(page, *link) {
+		return
+	}
+	if ref.imported && link.Type == LinkTypeInternal {
+		c.checkStylesheet(ctx, source, *link, depth+1)
+		return
+	}
+	c.checkAsset(ctx, source, *link)
+}
+
+func (c *crawler) cssLink(target *url.URL, element string, line int) *Link {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil
+	}
+	target.Fragment = ""
+	normalized := c.normalizeURL(target.String())
+	if normalized == "" {
+		return nil
+	}
+	linkType := LinkTypeExternal
+	if strings.EqualFold(target.Host, c.start.Host) {
+		linkType = LinkTypeInternal
+	}
+	return &Link{URL: normalized, Type: linkType, Element: element, Line: line}
+}
+
+// checkAsset queues a status check of a resource referenced from CSS.
+// External resources are only checked when Config.AllowExternal is set.
+func (c *crawler) checkAsset(ctx context.Context, source string, link Link) {
+	c.recordReferrers(source, []Link{link})
+	if link.Type == LinkTypeExternal && !c.allowExternal {
+		return
+	}
+	c.enqueueStatusCheck(ctx, externalJob{url: link.URL, source: source, via: &link, asset: link.Type == LinkTypeInternal})
+}
+
+// checkStylesheet fetches a same-site stylesheet once and validates its
+// references with the stylesheet as their source.
+func (c *crawler) checkStylesheet(ctx context.Context, source string, link Link, depth int) {
+	c.recordReferrers(source, []Link{link})
+	if depth > maxCSSImportDepth {
+		return
+	}
+	c.cssMu.Lock()
+	_, seen := c.cssChecked[link.URL]
+	c.cssChecked[link.URL] = struct{}{}
+	c.cssMu.Unlock()
+	if seen {
+		return
+	}
+
+	sheetURL, err := url.Parse(link.URL)
+	if err != nil {
+		return
+	}
+	if !c.allowedByRobots(ctx, sheetURL) {
+		c.recordSkippedRobots(link.URL)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	if !c.acquireRequestSlot(ctx) {
+		if ctx.Err() != nil {
+			c.recordUnvisited(link.URL)
+			return
+		}
+		c.recordError(Error{Source: source, Target: link.URL, Type: "rate", Message: "rate limit reached", Link: &link})
+		return
+	}
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.recordUnvisited(link.URL)
+			return
+		}
+		c.recordError(Error{Source: source, Target: link.URL, Type: "request", Message: err.Error(), Link: &link})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		c.recordError(Error{Source: source, Target: link.URL, Type: "http", Message: fmt.Sprintf("status %d", resp.StatusCode), Status: resp.StatusCode, Link: &link})
+		return
+	}
+	css, err := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
+	if err != nil {
+		if ctx.Err() != nil {
+			c.recordUnvisited(link.URL)
+			return
+		}
+		c.recordError(Error{Source: source, Target: link.URL, Type: "read", Message: err.Error(), Link: &link})
+		return
+	}
+
+	final := link.URL
+	if resp.Request != nil && resp.Request.URL != nil {
+		final = resp.Request.URL.String()
+	}
+	base, err := url.Parse(final)
+	if err != nil {
+		return
+	}
+	lines := newLineIndex(css)
+	for _, ref := range parseCSSReferences(css) {
+		c.checkCSSReference(ctx, nil, link.URL, base, ref, lines.line(ref.offset), depth)
+	}
+}
@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultNearDuplicateDistance is the largest SimHash Hamming distance at
+// which Crawl treats two pages as near duplicates.
+const DefaultNearDuplicateDistance = 3
+
+// simHashShingle is the number of consecutive words hashed as one feature.
+const simHashShingle = 3
+
+// fingerprintContent stores the content hash and SimHash of a page. text is
+// the cleaned page text returned by pageText, so ContentHash equals the
+// content_sha256 of the markdown export. The SimHash also leaves out a layout
+// that was not yet confirmed when the page was crawled; otherwise the shared
+// header and footer would make the first short pages of a site look alike.
+func (c *crawler) fingerprintContent(page *PageReport, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	page.ContentHash = contentHash(text)
+	page.SimHash = simHash(c.withoutLayout(page.URL, text))
+}
+
+// simHash computes a 64-bit SimHash over shingles of lower-cased words. Texts
+// that share most of their shingles produce hashes with a small Hamming
+// distance. Texts too short to form a shingle hash to zero.
+func simHash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < simHashShingle {
+		return 0
+	}
+	var weights [64]int
+	for i := 0; i+simHashShingle <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+simHashShingle], " ")))
+		feature := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var out uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			out |= 1 << bit
+		}
+	}
+	return out
+}
+
+// FindDuplicates groups healthy pages with identical content hashes into
+// exact clusters and pages whose SimHashes differ in at most maxDistance bits
+// into near clusters. Pages redirecting to the same URL count once, and a
+// negative maxDistance disables near-duplicate detection. Clusters and their
+// pages are sorted by URL.
+func FindDuplicates(pages map[string]*PageReport, maxDistance int) []DuplicateCluster {
+	type group struct {
+		hash    string
+		simHash uint64
+		pages   []string
+	}
+	seenFinal := map[string]struct{}{}
+	groups := map[string]*group{}
+	for _, key := range sortedPageKeys(pages) {
+		page := pages[key]
+		if page.ContentHash == "" || page.Error != "" || page.Status >= 400 {
+			continue
+		}
+		final := page.URL
+		if page.RedirectURL != "" {
+			final = page.RedirectURL
+		}
+		if _, ok := seenFinal[final]; ok {
+			continue
+		}
+		seenFinal[final] = struct{}{}
+		g, ok := groups[page.ContentHash]
+		if !ok {
+			g = &group{hash: page.ContentHash, simHash: page.SimHash}
+			groups[page.ContentHash] = g
+		}
+		g.pages = append(g.pages, page.URL)
+	}
+
+	ordered := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].pages[0] < ordered[j].pages[0] })
+
+	var clusters []DuplicateCluster
+	for _, g := range ordered {
+		if len(g.pages) > 1 {
+			clusters = append(clusters, DuplicateCluster{Kind: DuplicateExact, ContentHash: g.hash, Pages: g.pages})
+		}
+	}
+
+	if maxDistance >= 0 {
+		parent := make([]int, len(ordered))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+		for i := range ordered {
+			if ordered[i].simHash == 0 {
+				continue
+			}
+			for j := i + 1; j < len(ordered); j++ {
+				if ordered[j].simHash == 0 {
+					continue
+				}
+				if bits.OnesCount64(ordered[i].simHash^ordered[j].simHash) <= maxDistance {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+		members := map[int][]string{}
+		var roots []int
+		for i, g := range ordered {
+			root := find(i)
+			if _, ok := members[root]; !ok {
+				roots = append(roots, root)
+			}
+			members[root] = append(members[root], g.pages...)
+		}
+		for _, root := range roots {
+			urls := members[root]
+			if len(urls) == len(ordered[root].pages) {
+				continue
+			}
+			sort.Strings(urls)
+			clusters = append(clusters, DuplicateCluster{Kind: DuplicateNear, Pages: urls})
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Pages[0] < clusters[j].Pages[0] })
+	return clusters
+}
+
+func sortedPageKeys(pages map[string]*PageReport) []string {
+	keys := make([]string, 0, len(pages))
+	for key, page := range pages {
+		if page != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
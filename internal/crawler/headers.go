@@ -0,0 +1,320 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CheckHeaders names the findings of AuditSecurityHeaders.
+const CheckHeaders = "headers"
+
+// minHSTSMaxAge is the shortest Strict-Transport-Security max-age, 180 days,
+// that is not reported as weak.
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// securityHeaderRules describes each rule reported by AuditSecurityHeaders.
+var securityHeaderRules = map[string]string{
+	"hsts-missing":                 "Strict-Transport-Security is missing",
+	"hsts-weak":                    "Strict-Transport-Security max-age is below 180 days",
+	"csp-missing":                  "Content-Security-Policy is missing",
+	"csp-weak":                     "Content-Security-Policy allows unsafe-inline, unsafe-eval or any source for scripts",
+	"csp-no-script-policy":         "Content-Security-Policy sets neither script-src nor default-src, so scripts are unrestricted",
+	"content-type-options-missing": "X-Content-Type-Options is missing",
+	"content-type-options-weak":    "X-Content-Type-Options is not nosniff",
+	"frame-options-missing":        "neither X-Frame-Options nor CSP frame-ancestors is set",
+	"frame-options-weak":           "X-Frame-Options is neither DENY nor SAMEORIGIN",
+	"referrer-policy-missing":      "Referrer-Policy is missing",
+	"referrer-policy-weak":         "Referrer-Policy leaks full URLs to other origins",
+	"cookie-secure-missing":        "cookie set without Secure",
+	"cookie-httponly-missing":      "cookie set without HttpOnly",
+	"cookie-samesite-missing":      "cookie set without SameSite",
+}
+
+// extractSecurityHeaders records the security-relevant headers of resp.
+// Multiple Content-Security-Policy headers are joined with commas.
+func extractSecurityHeaders(resp *http.Response) *SecurityHeaders {
+	h := &SecurityHeaders{
+		StrictTransportSecurity: resp.Header.Get("Strict-Transport-Security"),
+		ContentSecurityPolicy:   strings.Join(resp.Header.Values("Content-Security-Policy"), ", "),
+		ContentTypeOptions:      resp.Header.Get("X-Content-Type-Options"),
+		FrameOptions:            resp.Header.Get("X-Frame-Options"),
+		ReferrerPolicy:          resp.Header.Get("Referrer-Policy"),
+	}
+	for _, cookie := range resp.Cookies() {
+		flags := CookieFlags{Name: cookie.Name, Secure: cookie.Secure, HTTPOnly: cookie.HttpOnly}
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			flags.SameSite = "Lax"
+		case http.SameSiteStrictMode:
+			flags.SameSite = "Strict"
+		case http.SameSiteNoneMode:
+			flags.SameSite = "None"
+		}
+		h.Cookies = append(h.Cookies, flags)
+	}
+	return h
+}
+
+type headerIssue struct {
+	rule  string
+	value string
+}
+
+// securityHeaderIssues lists the problems of one response. HSTS and the
+// Secure cookie flag are only expected on https pages.
+func securityHeaderIssues(h *SecurityHeaders, secure bool) []headerIssue {
+	var issues []headerIssue
+	add := func(rule, value string) {
+		issues = append(issues, headerIssue{rule: rule, value: value})
+	}
+
+	if secure {
+		switch maxAge, ok := hstsMaxAge(h.StrictTransportSecurity); {
+		case h.StrictTransportSecurity == "":
+			add("hsts-missing", "")
+		case !ok || maxAge < minHSTSMaxAge:
+			add("hsts-weak", h.StrictTransportSecurity)
+		}
+	}
+
+	directives := cspDirectives(h.ContentSecurityPolicy)
+	switch {
+	case h.ContentSecurityPolicy == "":
+		add("csp-missing", "")
+	case !cspRestrictsScripts(directives):
+		add("csp-no-script-policy", h.ContentSecurityPolicy)
+	case cspAllowsUnsafeScripts(directives):
+		add("csp-weak", h.ContentSecurityPolicy)
+	}
+
+	switch {
+	case h.ContentTypeOptions == "":
+		add("content-type-options-missing", "")
+	case !strings.EqualFold(strings.TrimSpace(h.ContentTypeOptions), "nosniff"):
+		add("content-type-options-weak", h.ContentTypeOptions)
+	}
+
+	if _, ok := directives["frame-ancestors"]; !ok {
+		switch frame := strings.ToUpper(strings.TrimSpace(h.FrameOptions)); {
+		case frame == "":
+			add("frame-options-missing", "")
+		case frame != "DENY" && frame != "SAMEORIGIN":
+			add("frame-options-weak", h.FrameOptions)
+		}
+	}
+
+	switch policy := effectiveReferrerPolicy(h.ReferrerPolicy); policy {
+	case "":
+		add("referrer-policy-missing", "")
+	case "unsafe-url", "no-referrer-when-downgrade":
+		add("referrer-policy-weak", h.ReferrerPolicy)
+	}
+
+	for _, cookie := range h.Cookies {
+		if secure && !cookie.Secure {
+			add("cookie-secure-missing", cookie.Name)
+		}
+		if !cookie.HTTPOnly {
+			add("cookie-httponly-missing", cookie.Name)
+		}
+		if cookie.SameSite == "" {
+			add("cookie-samesite-missing", cookie.Name)
+		}
+	}
+	return issues
+}
+
+// hstsMaxAge parses the max-age directive of a Strict-Transport-Security
+// header.
+func hstsMaxAge(value string) (int, bool) {
+	for _, directive := range strings.Split(value, ";") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "max-age") {
+			continue
+		}
+		maxAge, err := strconv.Atoi(strings.Trim(strings.TrimSpace(raw), `"`))
+		return maxAge, err == nil
+	}
+	return 0, false
+}
+
+// cspDirectives maps the lower-cased directive names of a policy to their
+// source lists. When several policies are given, the first occurrence of a
+// directive wins.
+func cspDirectives(policy string) map[string][]string {
+	directives := map[string][]string{}
+	for _, part := range strings.FieldsFunc(policy, func(r rune) bool { return r == ';' || r == ',' }) {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, exists := directives[name]; !exists {
+			directives[name] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// cspRestrictsScripts reports whether the policy has a directive that governs
+// scripts at all.
+func cspRestrictsScripts(directives map[string][]string) bool {
+	_, script := directives["script-src"]
+	_, fallback := directives["default-src"]
+	return script || fallback
+}
+
+// cspAllowsUnsafeScripts reports whether the source list that governs scripts
+// allows inline code, eval or any host. Policies without one are reported by
+// cspRestrictsScripts instead.
+func cspAllowsUnsafeScripts(directives map[string][]string) bool {
+	sources, ok := directives["script-src"]
+	if !ok {
+		sources = directives["default-src"]
+	}
+	for _, source := range sources {
+		switch strings.ToLower(source) {
+		case "'unsafe-inline'", "'unsafe-eval'", "*", "http:", "https:":
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveReferrerPolicy returns the last policy of a comma-separated list,
+// which is the one browsers apply.
+func effectiveReferrerPolicy(value string) string {
+	policies := strings.Split(value, ",")
+	return strings.ToLower(strings.TrimSpace(policies[len(policies)-1]))
+}
+
+// AuditSecurityHeaders aggregates the headers recorded on crawled pages per
+// host and per path prefix of up to prefixDepth directory segments, and
+// returns the groups sorted by host and prefix together with one finding per
+// prefix and rule. Groups covering a whole host have an empty Prefix.
+func AuditSecurityHeaders(r *Report, prefixDepth int) ([]HeaderGroup, []Finding) {
+	if r == nil {
+		return nil, nil
+	}
+	type affected struct {
+		pages   int
+		example string
+		value   string
+	}
+	type group struct {
+		HeaderGroup
+		base   string
+		issues map[string]*affected
+	}
+	groups := map[string]*group{}
+	groupFor := func(u *url.URL, prefix string) *group {
+		key := strings.ToLower(u.Host) + prefix
+		g, ok := groups[key]
+		if !ok {
+			g = &group{
+				HeaderGroup: HeaderGroup{Host: strings.ToLower(u.Host), Prefix: prefix, Issues: map[string]int{}},
+				base:        u.Scheme + "://" + u.Host,
+				issues:      map[string]*affected{},
+			}
+			groups[key] = g
+		}
+		return g
+	}
+
+	seenFinal := map[string]struct{}{}
+	for _, key := range sortedPageKeys(r.Pages) {
+		page := r.Pages[key]
+		if page.Headers == nil {
+			continue
+		}
+		final := page.URL
+		if page.RedirectURL != "" {
+			final = page.RedirectURL
+		}
+		if _, ok := seenFinal[final]; ok {
+			continue
+		}
+		seenFinal[final] = struct{}{}
+		u, err := url.Parse(final)
+		if err != nil {
+			continue
+		}
+		issues := securityHeaderIssues(page.Headers, u.Scheme == "https")
+		for _, g := range []*group{groupFor(u, ""), groupFor(u, PathPrefix(u.Path, prefixDepth))} {
+			g.Pages++
+			counted := map[string]struct{}{}
+			for _, issue := range issues {
+				a, ok := g.issues[issue.rule]
+				if !ok {
+					a = &affected{example: page.URL, value: issue.value}
+					g.issues[issue.rule] = a
+				}
+				if _, dup := counted[issue.rule]; !dup {
+					counted[issue.rule] = struct{}{}
+					a.pages++
+					g.Issues[issue.rule]++
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []HeaderGroup
+	var findings []Finding
+	for _, key := range keys {
+		g := groups[key]
+		result = append(result, g.HeaderGroup)
+		if g.Prefix == "" {
+			continue
+		}
+		for rule, a := range g.issues {
+			scope := g.base + g.Prefix
+			if !strings.HasSuffix(scope, "/") {
+				scope += "/"
+			}
+			findings = append(findings, Finding{
+				Check:    CheckHeaders,
+				Rule:     rule,
+				URL:      scope,
+				Message:  fmt.Sprintf("%s on %d of %d pages, e.g. %s", securityHeaderRules[rule], a.pages, g.Pages, a.example),
+				Snippet:  a.value,
+				Severity: SeverityWarning,
+			})
+		}
+	}
+	sortFindings(findings)
+	return result, findings
+}
+
+// PathPrefix returns up to depth leading directory segments of p as "/a/b",
+// or "/" for pages at the root. The final segment of a path without a
+// trailing slash names the page itself and never forms part of the prefix.
+// Depths below one are treated as one.
+func PathPrefix(p string, depth int) string {
+	if depth < 1 {
+		depth = 1
+	}
+	trimmed := strings.Trim(p, "/")
+	if trimmed == "" {
+		return "/"
+	}
+	segments := strings.Split(trimmed, "/")
+	if !strings.HasSuffix(p, "/") {
+		segments = segments[:len(segments)-1]
+	}
+	if len(segments) == 0 {
+		return "/"
+	}
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
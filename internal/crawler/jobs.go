@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"context"
 	"net/url"
 	"os"
 )
@@ -10,7 +11,7 @@ type internalJob struct {
 	depth int
 }
 
-func (c *crawler) enqueueInternal(raw string, depth int) {
+func (c *crawler) enqueueInternal(ctx context.Context, raw string, depth int) {
 	normalized := c.normalizeURL(raw)
 	if normalized == "" {
 		return
@@ -20,21 +21,21 @@ func (c *crawler) enqueueInternal(raw string, depth int) {
 		return
 	}
 	if !c.allowedExtension(parsed) {
-		c.recordSkippedExtension()
+		c.recordSkippedExtension(normalized)
 		return
 	}
 	if normalized != c.start.String() && c.shouldSkipCached(normalized) {
-		c.recordSkippedCache()
+		c.recordSkippedCache(normalized)
 		return
 	}
 	if c.maxDepth >= 0 && depth > c.maxDepth {
-		c.recordSkippedDepth()
+		c.recordSkippedDepth(normalized)
 		return
 	}
 	c.mu.Lock()
 	if c.maxPages > 0 && len(c.visitedInternal) >= c.maxPages {
 		c.mu.Unlock()
-		c.recordSkippedLimit()
+		c.recordSkippedLimit(normalized)
 		return
 	}
 	if _, seen := c.visitedInternal[normalized]; seen {
@@ -47,12 +48,18 @@ func (c *crawler) enqueueInternal(raw string, depth int) {
 	job := internalJob{url: normalized, depth: depth}
 	c.internalWG.Add(1)
 	if !c.trySendInternal(job) {
-		go c.waitSendInternal(job)
+		go c.waitSendInternal(ctx, job)
 	}
 }
 
-func (c *crawler) enqueueExternal(raw, source string) {
-	normalized := c.normalizeURL(raw)
+func (c *crawler) enqueueExternal(ctx context.Context, raw, source string, via *Link) {
+	c.enqueueStatusCheck(ctx, externalJob{url: raw, source: source, via: via})
+}
+
+// enqueueStatusCheck queues a URL whose status is checked without parsing
+// the response. Every URL is checked once.
+func (c *crawler) enqueueStatusCheck(ctx context.Context, job externalJob) {
+	normalized := c.normalizeURL(job.url)
 	if normalized == "" {
 		return
 	}
@@ -65,9 +72,9 @@ func (c *crawler) enqueueExternal(raw, source string) {
 	c.mu.Unlock()
 
 	c.externalWG.Add(1)
-	job := externalJob{url: normalized, source: source}
+	job.url = normalized
 	if !c.trySendExternal(job) {
-		go c.waitSendExternal(job)
+		go c.waitSendExternal(ctx, job)
 	}
 }
 
@@ -80,13 +87,18 @@ func (c *crawler) trySendInternal(job internalJob) bool {
 	}
 }
 
-func (c *crawler) waitSendInternal(job internalJob) {
+func (c *crawler) waitSendInternal(ctx context.Context, job internalJob) {
 	defer func() {
 		if recover() != nil {
 			c.internalWG.Done()
 		}
 	}()
-	c.internalJobs <- job
+	select {
+	case c.internalJobs <- job:
+	case <-ctx.Done():
+		c.recordUnvisited(job.url)
+		c.internalWG.Done()
+	}
 }
 
 func (c *crawler) trySendExternal(job externalJob) bool {
@@ -98,13 +110,18 @@ func (c *crawler) trySendExternal(job externalJob) bool {
 	}
 }
 
-func (c *crawler) waitSendExternal(job externalJob) {
+func (c *crawler) waitSendExternal(ctx context.Context, job externalJob) {
 	defer func() {
 		if recover() != nil {
 			c.externalWG.Done()
 		}
 	}()
-	c.externalJobs <- job
+	select {
+	case c.externalJobs <- job:
+	case <-ctx.Done():
+		c.recordUnvisited(job.url)
+		c.externalWG.Done()
+	}
 }
 
 func (c *crawler) shouldSkipCached(normalized string) bool {
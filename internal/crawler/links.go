@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"bytes"
 	"html"
 	"net/url"
 	"path"
@@ -8,10 +9,14 @@ import (
 	"strings"
 )
 
-var linkPattern = regexp.MustCompile(`(?i)<a[^>]*?\bhref\s*=\s*("([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+var (
+	linkPattern        = regexp.MustCompile(`(?i)<a[^>]*?\bhref\s*=\s*("([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	anchorClosePattern = regexp.MustCompile(`(?i)</a\s*>`)
+	attributePattern   = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*("([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
 
 func (c *crawler) extractLinks(body []byte, base string) []Link {
-	matches := linkPattern.FindAllSubmatch(body, -1)
+	matches := linkPattern.FindAllSubmatchIndex(body, -1)
 	if len(matches) == 0 {
 		return nil
 	}
@@ -22,16 +27,17 @@ func (c *crawler) extractLinks(body []byte, base string) []Link {
 
 	seen := make(map[string]struct{})
 	links := make([]Link, 0, len(matches))
+	line, lineOffset := 1, 0
 
 	for _, m := range matches {
 		href := ""
 		switch {
-		case len(m) >= 3 && len(m[2]) > 0:
-			href = string(m[2])
-		case len(m) >= 4 && len(m[3]) > 0:
-			href = string(m[3])
-		case len(m) >= 5 && len(m[4]) > 0:
-			href = string(m[4])
+		case m[4] >= 0 && m[5] > m[4]:
+			href = string(body[m[4]:m[5]])
+		case m[6] >= 0 && m[7] > m[6]:
+			href = string(body[m[6]:m[7]])
+		case m[8] >= 0 && m[9] > m[8]:
+			href = string(body[m[8]:m[9]])
 		}
 		href = html.UnescapeString(strings.TrimSpace(href))
 		if href == "" {
@@ -71,12 +77,63 @@ func (c *crawler) extractLinks(body []byte, base string) []Link {
 		if strings.EqualFold(candidate.Host, c.start.Host) {
 			linkType = LinkTypeInternal
 		}
-		links = append(links, Link{URL: normalized, Type: linkType})
+		line += bytes.Count(body[lineOffset:m[0]], []byte("\n"))
+		lineOffset = m[0]
+		link := Link{
+			URL:     normalized,
+			Type:    linkType,
+			Element: LinkElementAnchor,
+			Line:    line,
+		}
+		if end := bytes.IndexByte(body[m[1]:], '>'); end >= 0 {
+			tagEnd := m[1] + end
+			attrs := parseTagAttributes(body[m[0]+2 : tagEnd])
+			link.Rel = strings.Fields(strings.ToLower(attrs["rel"]))
+			link.Target = attrs["target"]
+			link.Title = attrs["title"]
+			link.Text = anchorText(body[tagEnd+1:])
+		}
+		links = append(links, link)
 	}
 
 	return links
 }
 
+// parseTagAttributes returns the attributes of an HTML start tag body, keyed
+// by lower-cased name. Values are unescaped and trimmed; attributes without a
+// value map to the empty string.
+func parseTagAttributes(tag []byte) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attributePattern.FindAllSubmatch(tag, -1) {
+		name := strings.ToLower(string(m[1]))
+		if _, exists := attrs[name]; exists {
+			continue
+		}
+		value := ""
+		switch {
+		case m[3] != nil:
+			value = string(m[3])
+		case m[4] != nil:
+			value = string(m[4])
+		case m[5] != nil:
+			value = string(m[5])
+		}
+		attrs[name] = strings.TrimSpace(html.UnescapeString(value))
+	}
+	return attrs
+}
+
+// anchorText returns the visible text of an anchor whose content starts at
+// the beginning of inner, with nested markup removed and whitespace collapsed.
+func anchorText(inner []byte) string {
+	closing := anchorClosePattern.FindIndex(inner)
+	if closing == nil {
+		return ""
+	}
+	text := fallbackTagPattern.ReplaceAll(inner[:closing[0]], []byte(" "))
+	return collapseUnicodeSpaces(html.UnescapeString(string(text)))
+}
+
 func buildAllowedExtensions(list []string) map[string]struct{} {
 	allowed := make(map[string]struct{})
 	if len(list) == 0 {
@@ -31,36 +31,20 @@ type boilerplateInfo struct {
 	footerMatches   int
 }
 
-func (c *crawler) writeMarkdown(page *PageReport, body []byte, visitedAt time.Time) {
+// writeMarkdown exports a page. text is the cleaned page text returned by
+// pageText and convertErr the error of convertToMarkdown, which the caller
+// has already run.
+func (c *crawler) writeMarkdown(page *PageReport, text string, convertErr error, visitedAt time.Time) {
 	if c == nil || c.markdownDir == "" || page == nil {
 		return
 	}
 
-	parsedURL, err := url.Parse(page.URL)
-	if err != nil {
-		return
-	}
-
-	converter := htmltomarkdown.NewConverter(parsedURL.String(), true, nil)
-	markdown, err := converter.ConvertString(string(body))
-	if err != nil {
-		c.recordError(Error{Source: page.URL, Target: page.URL, Type: "markdown", Message: err.Error()})
+	if convertErr != nil {
+		c.recordError(Error{Source: page.URL, Target: page.URL, Type: "markdown", Message: convertErr.Error()})
 		return
 	}
 
-	trimmed := strings.TrimSpace(markdown)
-	text := trimmed
-	if trimmed != "" {
-		cleaned := strings.TrimSpace(c.removeBoilerplate(parsedURL, trimmed))
-		if cleaned != "" {
-			text = cleaned
-		}
-	} else {
-		text = buildEmptyContentFallback(body)
-	}
-
-	sum := sha256.Sum256([]byte(text))
-	hash := hex.EncodeToString(sum[:])
+	hash := contentHash(text)
 
 	target, err := c.markdownFilePath(page.URL)
 	if err != nil {
@@ -93,6 +77,40 @@ func (c *crawler) writeMarkdown(page *PageReport, body []byte, visitedAt time.Ti
 	page.MarkdownSkippedReason = ""
 }
 
+// pageText returns the text of a page as exported to markdown: trimmed, the
+// result of convertToMarkdown, with the host's confirmed header and footer
+// removed, or a fallback built from body when the page has no markdown.
+func (c *crawler) pageText(pageURL string, trimmed string, body []byte) string {
+	if trimmed == "" {
+		return buildEmptyContentFallback(body)
+	}
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return trimmed
+	}
+	if cleaned := strings.TrimSpace(c.removeBoilerplate(parsed, trimmed)); cleaned != "" {
+		return cleaned
+	}
+	return trimmed
+}
+
+// convertToMarkdown renders an HTML body as trimmed markdown.
+func convertToMarkdown(pageURL string, body []byte) (string, error) {
+	converter := htmltomarkdown.NewConverter(pageURL, true, nil)
+	markdown, err := converter.ConvertString(string(body))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(markdown), nil
+}
+
+// contentHash returns the hex SHA-256 of page text, as stored in the
+// content_sha256 frontmatter field.
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
 func countLinkTypes(links []Link) (int, int) {
 	var internal, external int
 	for _, link := range links {
@@ -121,12 +139,43 @@ func buildMarkdownDocument(page *PageReport, body string, visitedAt time.Time, h
 	if page.Error != "" {
 		builder.WriteString(fmt.Sprintf("error: %q\n", page.Error))
 	}
+	writeFrontmatterLinks(&builder, page.Links)
 	builder.WriteString("---\n\n")
 	builder.WriteString(body)
 	builder.WriteString("\n")
 	return builder.String()
 }
 
+func writeFrontmatterLinks(builder *strings.Builder, links []Link) {
+	if len(links) == 0 {
+		return
+	}
+	builder.WriteString("links:\n")
+	for _, link := range links {
+		builder.WriteString(fmt.Sprintf("  - url: %q\n", link.URL))
+		builder.WriteString(fmt.Sprintf("    type: %s\n", link.Type))
+		if link.Element != "" {
+			builder.WriteString(fmt.Sprintf("    element: %s\n", link.Element))
+		}
+		if link.Text != "" {
+			builder.WriteString(fmt.Sprintf("    text: %q\n", link.Text))
+		}
+		if link.Title != "" {
+			builder.WriteString(fmt.Sprintf("    title: %q\n", link.Title))
+		}
+		if link.Target != "" {
+			builder.WriteString(fmt.Sprintf("    target: %q\n", link.Target))
+		}
+		if len(link.Rel) > 0 {
+			quoted := make([]string, len(link.Rel))
+			for i, rel := range link.Rel {
+				quoted[i] = fmt.Sprintf("%q", rel)
+			}
+			builder.WriteString(fmt.Sprintf("    rel: [%s]\n", strings.Join(quoted, ", ")))
+		}
+	}
+}
+
 func wordCount(body string) int {
 	if body == "" {
 		return 0
@@ -485,6 +534,32 @@ func (c *crawler) removeBoilerplate(parsed *url.URL, markdown string) string {
 	return strings.Join(trimmed, "\n")
 }
 
+// withoutLayout removes the host's header and footer candidates from text
+// even before removeBoilerplate has confirmed them, so that pages crawled
+// early still compare by their own content. The boilerplate state is left
+// unchanged.
+func (c *crawler) withoutLayout(pageURL string, text string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return text
+	}
+	c.boilerplateMu.Lock()
+	info := c.boilerplates[strings.ToLower(parsed.Host)]
+	var header, footer []string
+	if info != nil {
+		header, footer = info.headerCandidate, info.footerCandidate
+	}
+	c.boilerplateMu.Unlock()
+	lines := splitLines(text)
+	lines, _ = matchAndRemoveHeader(lines, header)
+	lines, _ = matchAndRemoveFooter(lines, footer)
+	if len(trimEmptyEdges(lines)) == 0 {
+		// The page consists of nothing but the candidates.
+		return text
+	}
+	return strings.Join(lines, "\n")
+}
+
 func splitLines(input string) []string {
 	if input == "" {
 		return nil
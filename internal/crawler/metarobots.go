@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// robotsParameters are the X-Robots-Tag rules that take a value after a
+// colon and must not be mistaken for a user agent prefix.
+var robotsParameters = map[string]struct{}{
+	"unavailable_after": {},
+	"max-snippet":       {},
+	"max-image-preview": {},
+	"max-video-preview": {},
+}
+
+// robotsDirectives holds the page-level indexing directives of a response.
+type robotsDirectives struct {
+	noindex  bool
+	nofollow bool
+}
+
+func (d *robotsDirectives) add(list string) {
+	for _, token := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(token)) {
+		case "noindex":
+			d.noindex = true
+		case "nofollow":
+			d.nofollow = true
+		case "none":
+			d.noindex = true
+			d.nofollow = true
+		}
+	}
+}
+
+// pageRobotsDirectives reads the X-Robots-Tag headers of a response and, for
+// HTML bodies, the <meta name="robots"> tags. Directives addressed to
+// another user agent, such as "googlebot: noindex", are ignored.
+func pageRobotsDirectives(header http.Header, body []byte, parseable bool) robotsDirectives {
+	agent := strings.ToLower(strings.Split(defaultUserAgent, "/")[0])
+	var d robotsDirectives
+	for _, value := range header.Values("X-Robots-Tag") {
+		if name, rest, found := strings.Cut(value, ":"); found {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, parameter := robotsParameters[name]; !parameter && !strings.Contains(name, ",") {
+				if name != agent {
+					continue
+				}
+				value = rest
+			}
+		}
+		d.add(value)
+	}
+	if !parseable {
+		return d
+	}
+	for _, m := range metaTagPattern.FindAllSubmatch(maskHiddenMarkup(body), -1) {
+		attrs := parseTagAttributes(m[1])
+		if name := strings.ToLower(attrs["name"]); name == "robots" || name == agent {
+			d.add(attrs["content"])
+		}
+	}
+	return d
+}
+
+// withheldLink records and reports whether a link of page must not be
+// fetched. Unless robots directives are ignored, a page-level nofollow
+// directive withholds every link, relation and stylesheet reference of the
+// page, and Config.SkipNofollowLinks withholds links marked rel="nofollow".
+// External links are only withheld when they would be checked at all.
+func (c *crawler) withheldLink(page *PageReport, link Link) bool {
+	switch {
+	case link.Type == LinkTypeExternal && !c.allowExternal:
+		return false
+	case page.NoFollow && c.obeyMetaRobots:
+		c.recordSkippedNofollow(link.URL)
+	case c.skipRelNofollow && link.HasRel("nofollow"):
+		c.recordSkippedRelNofollow(link.URL)
+	default:
+		return false
+	}
+	return true
+}
+
+// collectNoIndex returns the sorted URLs of the pages marked noindex.
+func (c *crawler) collectNoIndex() []string {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	var urls []string
+	for u, page := range c.pages {
+		if page.NoIndex {
+			urls = append(urls, u)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mixedContentAttributes maps tags that load subresources to the attribute
+// holding the URL and whether the resource is active content, which can
+// change the page and is blocked by browsers, or passive content.
+var mixedContentAttributes = map[string]struct {
+	attr   string
+	active bool
+}{
+	"script": {"src", true},
+	"iframe": {"src", true},
+	"frame":  {"src", true},
+	"embed":  {"src", true},
+	"object": {"data", true},
+	"img":    {"src", false},
+	"audio":  {"src", false},
+	"video":  {"src", false},
+	"source": {"src", false},
+	"track":  {"src", false},
+}
+
+// checkMixedContent reports http subresources and http links to the crawled
+// host on a page served over https.
+func (c *crawler) checkMixedContent(ctx context.Context, page *PageReport, body []byte, links []Link) {
+	final := page.URL
+	if page.RedirectURL != "" {
+		final = page.RedirectURL
+	}
+	base, err := url.Parse(final)
+	if err != nil || base.Scheme != "https" {
+		return
+	}
+
+	masked := maskHiddenMarkup(body)
+	lines := newLineIndex(masked)
+	seen := map[string]struct{}{}
+	for _, m := range startTagPattern.FindAllSubmatchIndex(masked, -1) {
+		name := strings.ToLower(string(masked[m[2]:m[3]]))
+		attrs := parseTagAttributes(masked[m[4]:m[5]])
+		spec, ok := mixedContentAttributes[name]
+		raw := attrs[spec.attr]
+		active := spec.active
+		if name == "link" {
+			rel := strings.ToLower(attrs["rel"])
+			if !hasToken(rel, "stylesheet") && !hasToken(rel, "icon") && !hasToken(rel, "preload") && !hasToken(rel, "modulepreload") {
+				continue
+			}
+			ok, raw, active = true, attrs["href"], !hasToken(rel, "icon")
+		}
+		if !ok || raw == "" {
+			continue
+		}
+		ref, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		target := base.ResolveReference(ref)
+		if target.Scheme != "http" {
+			continue
+		}
+		target.Fragment = ""
+		if _, dup := seen[target.String()]; dup {
+			continue
+		}
+		seen[target.String()] = struct{}{}
+
+		kind := "passive"
+		if active {
+			kind = "active"
+		}
+		linkType := LinkTypeExternal
+		if strings.EqualFold(target.Host, c.start.Host) {
+			linkType = LinkTypeInternal
+		}
+		msg := fmt.Sprintf("%s mixed content: <%s> loads %s over http", kind, name, target)
+		c.recordError(Error{
+			Source:  page.URL,
+			Target:  target.String(),
+			Type:    "mixed-content",
+			Message: c.withHTTPSAvailability(ctx, msg, target),
+			Link:    &Link{URL: target.String(), Type: linkType, Element: name, Line: lines.line(m[0])},
+		})
+	}
+
+	for i := range links {
+		link := links[i]
+		if link.Type != LinkTypeInternal || !strings.HasPrefix(link.URL, "http://") {
+			continue
+		}
+		target, err := url.Parse(link.URL)
+		if err != nil {
+			continue
+		}
+		msg := fmt.Sprintf("https page links to insecure %s", link.URL)
+		c.recordError(Error{
+			Source:  page.URL,
+			Target:  link.URL,
+			Type:    "insecure-link",
+			Message: c.withHTTPSAvailability(ctx, msg, target),
+			Link:    &link,
+		})
+	}
+}
+
+// withHTTPSAvailability appends to msg whether target is also served over
+// https, when Config.ProbeHTTPS is set.
+func (c *crawler) withHTTPSAvailability(ctx context.Context, msg string, target *url.URL) string {
+	if !c.probeHTTPS {
+		return msg
+	}
+	secure := *target
+	secure.Scheme = "https"
+	switch c.checkHTTPS(ctx, secure.String()) {
+	case httpsAvailable:
+		return msg + "; " + secure.String() + " is available"
+	case httpsUnavailable:
+		return msg + "; no https equivalent"
+	default:
+		return msg
+	}
+}
+
+type httpsAvailability int
+
+const (
+	httpsUnknown httpsAvailability = iota
+	httpsAvailable
+	httpsUnavailable
+)
+
+// checkHTTPS checks once per URL whether an https URL answers below 400.
+func (c *crawler) checkHTTPS(ctx context.Context, secureURL string) httpsAvailability {
+	c.httpsMu.Lock()
+	result, ok := c.httpsChecked[secureURL]
+	c.httpsMu.Unlock()
+	if ok {
+		return result
+	}
+
+	result = httpsUnknown
+	if c.acquireRequestSlot(ctx) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, secureURL, nil)
+		if err == nil {
+			req.Header.Set("User-Agent", defaultUserAgent)
+			resp, err := c.client.Do(req)
+			switch {
+			case err == nil && resp.StatusCode < 400 && resp.Request.URL.Scheme == "https":
+				result = httpsAvailable
+			case ctx.Err() == nil:
+				result = httpsUnavailable
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return httpsUnknown
+	}
+	c.httpsMu.Lock()
+	c.httpsChecked[secureURL] = result
+	c.httpsMu.Unlock()
+	return result
+}
@@ -0,0 +1,614 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfMediaType is the media type whose bodies are scanned for link
+// annotations.
+const pdfMediaType = "application/pdf"
+
+// maxPDFSize bounds the size of PDF documents read for link annotations.
+// Pages and other responses are read up to maxPageSize.
+var maxPDFSize int64 = 50 << 20
+
+// maxPDFStreamSize bounds the inflated size of an object stream.
+const maxPDFStreamSize = 16 << 20
+
+var (
+	pdfObjectPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRootPattern   = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R\b`)
+
+	errPDFEncrypted = errors.New("encrypted PDF documents are not supported")
+	errPDFNesting   = fmt.Errorf("values nested deeper than %d levels", maxPDFNesting)
+)
+
+// PDF values are decoded into these types. Dictionary keys and names are
+// stored without their leading slash.
+type (
+	pdfDict   map[string]any
+	pdfArray  []any
+	pdfName   string
+	pdfString string
+	pdfRef    struct{ num, gen int }
+	pdfStream struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+// pdfKeyword is a bare keyword such as "stream" or "endobj".
+type pdfKeyword string
+
+// pdfDocument holds the objects of a PDF file by object number. Objects are
+// found by scanning the file rather than through the cross-reference table,
+// so damaged or incrementally updated files still yield their links; the last
+// definition of an object wins.
+type pdfDocument struct {
+	objects map[int]any
+	root    int
+}
+
+// extractPDFLinks returns the URI link annotations of a PDF document, resolved
+// against base. Link.Page holds the 1-based page of each annotation; a URL
+// linked from several pages is reported once, for the first page.
+func (c *crawler) extractPDFLinks(body []byte, base string) ([]Link, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parsePDF(body)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	var links []Link
+	for i, page := range doc.pages() {
+		annots, _ := doc.resolve(page["Annots"]).(pdfArray)
+		for _, item := range annots {
+			annot, _ := doc.resolve(item).(pdfDict)
+			if name, _ := annot["Subtype"].(pdfName); name != "Link" {
+				continue
+			}
+			action, _ := doc.resolve(annot["A"]).(pdfDict)
+			if kind, _ := doc.resolve(action["S"]).(pdfName); kind != "URI" {
+				continue
+			}
+			raw, _ := doc.resolve(action["URI"]).(pdfString)
+			href := strings.TrimSpace(string(raw))
+			if href == "" || strings.HasPrefix(strings.ToLower(href), "mailto:") {
+				continue
+			}
+			candidate, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			candidate = baseURL.ResolveReference(candidate)
+			candidate.Fragment = ""
+			normalized := c.normalizeURL(candidate.String())
+			if normalized == "" {
+				continue
+			}
+			if _, dup := seen[normalized]; dup {
+				continue
+			}
+			seen[normalized] = struct{}{}
+			linkType := LinkTypeExternal
+			if strings.EqualFold(candidate.Host, c.start.Host) {
+				linkType = LinkTypeInternal
+			}
+			contents, _ := doc.resolve(annot["Contents"]).(pdfString)
+			links = append(links, Link{
+				URL:     normalized,
+				Type:    linkType,
+				Element: LinkElementPDF,
+				Text:    collapseUnicodeSpaces(decodePDFText(contents)),
+				Page:    i + 1,
+			})
+		}
+	}
+	return links, nil
+}
+
+// parsePDF collects every object of a PDF file, including those packed into
+// compressed object streams.
+func parsePDF(body []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(body, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, errors.New("missing %PDF header")
+	}
+	if bytes.Contains(body, []byte("/Encrypt")) {
+		return nil, errPDFEncrypted
+	}
+	doc := &pdfDocument{objects: map[int]any{}}
+	for pos := 0; pos < len(body); {
+		loc := pdfObjectPattern.FindSubmatchIndex(body[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(body[pos+loc[2] : pos+loc[3]]))
+		p := &pdfParser{data: body, pos: pos + loc[1]}
+		value, err := p.value()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok && p.keyword() == "stream" {
+			stream := pdfStream{dict: dict}
+			stream.data, p.pos = streamData(body, p.pos, dict)
+			value = stream
+		}
+		doc.objects[num] = value
+		pos = p.pos
+	}
+	if len(doc.objects) == 0 {
+		return nil, errors.New("no objects found")
+	}
+
+	var packed []pdfStream
+	for _, value := range doc.objects {
+		if stream, ok := value.(pdfStream); ok && stream.dict["Type"] == pdfName("ObjStm") {
+			packed = append(packed, stream)
+		}
+	}
+	for _, stream := range packed {
+		doc.unpackObjectStream(stream)
+	}
+
+	if m := pdfRootPattern.FindAllSubmatch(body, -1); len(m) > 0 {
+		doc.root, _ = strconv.Atoi(string(m[len(m)-1][1]))
+	}
+	return doc, nil
+}
+
+// streamData returns the data of a stream whose "stream" keyword ends at pos,
+// and the position after its "endstream" keyword.
+func streamData(body []byte, pos int, dict pdfDict) ([]byte, int) {
+	switch {
+	case bytes.HasPrefix(body[pos:], []byte("\r\n")):
+		pos += 2
+	case bytes.HasPrefix(body[pos:], []byte("\n")), bytes.HasPrefix(body[pos:], []byte("\r")):
+		pos++
+	}
+	if length, ok := dict["Length"].(int); ok && length >= 0 && length <= len(body)-pos {
+		rest := bytes.TrimLeft(body[pos+length:], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return body[pos : pos+length], len(body) - len(rest) + len("endstream")
+		}
+	}
+	end := bytes.Index(body[pos:], []byte("endstream"))
+	if end < 0 {
+		return body[pos:], len(body)
+	}
+	return bytes.TrimRight(body[pos:pos+end], "\r\n"), pos + end + len("endstream")
+}
+
+// unpackObjectStream adds the objects packed into an object stream. Objects
+// defined directly in the file take precedence.
+func (d *pdfDocument) unpackObjectStream(stream pdfStream) {
+	data, err := d.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	count, _ := d.resolve(stream.dict["N"]).(int)
+	first, _ := d.resolve(stream.dict["First"]).(int)
+	if first <= 0 || first > len(data) {
+		return
+	}
+	header := &pdfParser{data: data[:first]}
+	for i := 0; i < count; i++ {
+		num, err1 := header.value()
+		offset, err2 := header.value()
+		n, ok1 := num.(int)
+		off, ok2 := offset.(int)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 || off < 0 || off >= len(data)-first {
+			return
+		}
+		if _, exists := d.objects[n]; exists {
+			continue
+		}
+		if value, err := (&pdfParser{data: data, pos: first + off}).value(); err == nil {
+			d.objects[n] = value
+		}
+	}
+}
+
+// decodeStream applies the stream's filters. Only FlateDecode is supported.
+func (d *pdfDocument) decodeStream(stream pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = append(filters, f)
+	case pdfArray:
+		for _, item := range f {
+			if name, ok := d.resolve(item).(pdfName); ok {
+				filters = append(filters, name)
+			}
+		}
+	}
+	data := stream.data
+	for _, filter := range filters {
+		if filter != "FlateDecode" {
+			return nil, fmt.Errorf("unsupported filter %s", filter)
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(io.LimitReader(r, maxPDFStreamSize))
+		r.Close()
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// resolve follows indirect references.
+func (d *pdfDocument) resolve(value any) any {
+	for range 32 {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = d.objects[ref.num]
+	}
+	return nil
+}
+
+// pages returns the page dictionaries in document order. Without a usable
+// page tree, page objects are returned in object number order.
+func (d *pdfDocument) pages() []pdfDict {
+	var pages []pdfDict
+	visited := map[int]struct{}{}
+	var walk func(value any)
+	walk = func(value any) {
+		if ref, ok := value.(pdfRef); ok {
+			if _, seen := visited[ref.num]; seen {
+				return
+			}
+			visited[ref.num] = struct{}{}
+		}
+		node, _ := d.resolve(value).(pdfDict)
+		if kids, ok := d.resolve(node["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid)
+			}
+			return
+		}
+		if node["Type"] == pdfName("Page") {
+			pages = append(pages, node)
+		}
+	}
+	if catalog, ok := d.resolve(pdfRef{num: d.root}).(pdfDict); ok {
+		walk(catalog["Pages"])
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(d.objects))
+	for num, value := range d.objects {
+		if dict, ok := value.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, d.objects[num].(pdfDict))
+	}
+	return pages
+}
+
+// decodePDFText decodes a text string, which is either UTF-16BE with a byte
+// order mark or PDFDocEncoding, treated here as Latin-1.
+func decodePDFText(s pdfString) string {
+	b := []byte(s)
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// maxPDFNesting bounds how deeply arrays and dictionaries may nest, so that
+// hostile documents cannot exhaust the stack.
+const maxPDFNesting = 256
+
+// pdfParser reads PDF values from data starting at pos. depth counts the
+// arrays and dictionaries currently open.
+type pdfParser struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case isPDFSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword consumes and returns a bare keyword, or returns "" and leaves the
+// position unchanged.
+func (p *pdfParser) keyword() pdfKeyword {
+	start := p.pos
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.data) && !isPDFSpace(p.data[end]) && !isPDFDelimiter(p.data[end]) {
+		end++
+	}
+	word := string(p.data[p.pos:end])
+	if word == "" || !isPDFKeyword(word) {
+		p.pos = start
+		return ""
+	}
+	p.pos = end
+	return pdfKeyword(word)
+}
+
+func isPDFKeyword(word string) bool {
+	for _, c := range word {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pdfParser) value() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		return p.dict()
+	case c == '<':
+		return p.hexString()
+	case c == '(':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+	switch word := p.keyword(); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.data[p.pos], p.pos)
+	default:
+		return word, nil
+	}
+}
+
+func (p *pdfParser) name() pdfName {
+	p.pos++
+	var b []byte
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelimiter(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if v, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		p.pos++
+	}
+	return pdfName(b)
+}
+
+func (p *pdfParser) dict() (pdfDict, error) {
+	p.pos += 2
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxPDFNesting {
+		return nil, errPDFNesting
+	}
+	dict := pdfDict{}
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return dict, nil
+		}
+		key, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("dictionary key is not a name at offset %d", p.pos)
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[string(name)] = value
+	}
+}
+
+func (p *pdfParser) array() (pdfArray, error) {
+	p.pos++
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxPDFNesting {
+		return nil, errPDFNesting
+	}
+	var array pdfArray
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+	}
+}
+
+// number reads an integer or real. An integer followed by a generation
+// number and "R" is an indirect reference.
+func (p *pdfParser) number() (any, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.data) && (p.data[p.pos] == '.' || (p.data[p.pos] >= '0' && p.data[p.pos] <= '9')) {
+		p.pos++
+	}
+	text := string(p.data[start:p.pos])
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at offset %d", text, start)
+		}
+		return f, nil
+	}
+
+	end := p.pos
+	p.skipSpace()
+	genStart := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > genStart {
+		gen, _ := strconv.Atoi(string(p.data[genStart:p.pos]))
+		if p.keyword() == "R" {
+			return pdfRef{num: n, gen: gen}, nil
+		}
+	}
+	p.pos = end
+	return n, nil
+}
+
+func (p *pdfParser) literalString() (pdfString, error) {
+	p.pos++
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				continue
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+func (p *pdfParser) hexString() (pdfString, error) {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			b := make([]byte, len(digits)/2)
+			for i := range b {
+				v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("bad hex string at offset %d", p.pos)
+				}
+				b[i] = byte(v)
+			}
+			return pdfString(b), nil
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return "", io.ErrUnexpectedEOF
+}
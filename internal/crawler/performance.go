@@ -0,0 +1,191 @@
+package crawler
+
+import (
+	"cmp"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckPerformance names the findings of AuditPerformance.
+const CheckPerformance = "performance"
+
+// requestTrace collects the httptrace events of the last connection a request
+// used. Events arrive from transport goroutines, hence the mutex.
+type requestTrace struct {
+	mu     sync.Mutex
+	events traceEvents
+}
+
+type traceEvents struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// traceRequest returns req with a client trace attached. Every connection the
+// client asks for resets the trace, so retried requests report the timing of
+// the last attempt and redirected requests that of the final hop.
+func traceRequest(req *http.Request) (*http.Request, *requestTrace) {
+	t := &requestTrace{}
+	e := &t.events
+	record := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			*e = traceEvents{start: time.Now()}
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { record(&e.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(&e.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if e.connectStart.IsZero() {
+				e.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone:          func(string, string, error) { record(&e.connectDone) },
+		TLSHandshakeStart:    func() { record(&e.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&e.tlsDone) },
+		GotFirstResponseByte: func() { record(&e.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// timing turns the trace into durations. sent and headers bracket the call to
+// the client and stand in for events a custom transport never reports; done
+// is the time the body was read.
+func (t *requestTrace) timing(sent, headers, done time.Time) *Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	e := t.events
+	start, firstByte := e.start, e.firstByte
+	if start.IsZero() {
+		start = sent
+	}
+	if firstByte.IsZero() {
+		firstByte = headers
+	}
+	return &Timing{
+		DNS:      span(e.dnsStart, e.dnsDone),
+		Connect:  span(e.connectStart, e.connectDone),
+		TLS:      span(e.tlsStart, e.tlsDone),
+		TTFB:     span(start, firstByte),
+		Download: span(firstByte, done),
+	}
+}
+
+// AuditPerformance summarizes the timings of crawled pages per host and per
+// path prefix of up to prefixDepth directory segments, sorted by host and
+// prefix, and reports pages whose time to first byte exceeds maxTTFB or whose
+// size exceeds maxWeight. Zero budgets are not checked.
+func AuditPerformance(r *Report, prefixDepth int, maxTTFB time.Duration, maxWeight int64) ([]PerformanceGroup, []Finding) {
+	if r == nil {
+		return nil, nil
+	}
+	type samples struct {
+		group PerformanceGroup
+		ttfb  []time.Duration
+		total []time.Duration
+		size  []int64
+	}
+	groups := map[string]*samples{}
+	var findings []Finding
+	for _, key := range sortedPageKeys(r.Pages) {
+		page := r.Pages[key]
+		if page.Timing == nil {
+			continue
+		}
+		u, err := url.Parse(page.URL)
+		if err != nil {
+			continue
+		}
+		for _, prefix := range []string{"", PathPrefix(u.Path, prefixDepth)} {
+			groupKey := strings.ToLower(u.Host) + prefix
+			s, ok := groups[groupKey]
+			if !ok {
+				s = &samples{group: PerformanceGroup{Host: strings.ToLower(u.Host), Prefix: prefix}}
+				groups[groupKey] = s
+			}
+			s.ttfb = append(s.ttfb, page.Timing.TTFB)
+			s.total = append(s.total, page.Retrieved)
+			s.size = append(s.size, page.Size)
+		}
+		if maxTTFB > 0 && page.Timing.TTFB > maxTTFB {
+			findings = append(findings, Finding{
+				Check:    CheckPerformance,
+				Rule:     "ttfb-budget",
+				URL:      page.URL,
+				Message:  fmt.Sprintf("time to first byte %s exceeds the budget of %s", page.Timing.TTFB.Round(time.Millisecond), maxTTFB),
+				Severity: SeverityWarning,
+			})
+		}
+		if maxWeight > 0 && page.Size > maxWeight {
+			findings = append(findings, Finding{
+				Check:    CheckPerformance,
+				Rule:     "page-weight-budget",
+				URL:      page.URL,
+				Message:  fmt.Sprintf("page weighs %d bytes, more than the budget of %d bytes", page.Size, maxWeight),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []PerformanceGroup
+	for _, key := range keys {
+		s := groups[key]
+		g := s.group
+		g.Pages = len(s.ttfb)
+		g.TTFB = latencyPercentiles(s.ttfb)
+		g.Retrieved = latencyPercentiles(s.total)
+		g.Size = SizePercentiles{P50: percentile(s.size, 50), P90: percentile(s.size, 90), P99: percentile(s.size, 99)}
+		result = append(result, g)
+	}
+	sortFindings(findings)
+	return result, findings
+}
+
+func latencyPercentiles(values []time.Duration) LatencyPercentiles {
+	return LatencyPercentiles{P50: percentile(values, 50), P90: percentile(values, 90), P99: percentile(values, 99)}
+}
+
+// percentile returns the nearest-rank percentile p of values, which it sorts
+// in place.
+func percentile[T cmp.Ordered](values []T, p float64) T {
+	var zero T
+	if len(values) == 0 {
+		return zero
+	}
+	slices.Sort(values)
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[max(rank, 1)-1]
+}
@@ -10,24 +10,26 @@ import (
 	"time"
 )
 
+// maxPageSize bounds the size of the responses read for internal pages.
+const maxPageSize int64 = 5 << 20
+
 func (c *crawler) processInternal(ctx context.Context, job internalJob) {
 	start := time.Now()
 	c.emitProgress(job.url)
 	parsed, err := url.Parse(job.url)
 	if err != nil {
 		c.recordError(Error{Source: job.url, Target: job.url, Type: "parse", Message: err.Error()})
-		c.savePage(&PageReport{URL: job.url, Error: err.Error()})
+		c.savePage(&PageReport{URL: job.url, Depth: job.depth, Error: err.Error()})
 		return
 	}
 	if !c.allowedByRobots(ctx, parsed) {
-		c.recordSkippedRobots()
+		c.recordSkippedRobots(job.url)
 		reason := "blocked by robots.txt"
-		page := &PageReport{URL: job.url, Error: reason}
+		page := &PageReport{URL: job.url, Depth: job.depth, Error: reason}
 		c.savePage(page)
 		c.updateCache(page, time.Now())
 		return
 	}
-	c.recordStatsVisit()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.url, nil)
 	if err != nil {
@@ -36,76 +38,234 @@ func (c *crawler) processInternal(ctx context.Context, job internalJob) {
 	}
 	req.Header.Set("User-Agent", defaultUserAgent)
 	if !c.acquireRequestSlot(ctx) {
+		if ctx.Err() != nil {
+			c.recordUnvisited(job.url)
+			return
+		}
 		reason := "rate limit reached"
 		c.recordError(Error{Source: job.url, Target: job.url, Type: "rate", Message: reason})
-		page := &PageReport{URL: job.url, Error: reason}
+		page := &PageReport{URL: job.url, Depth: job.depth, Error: reason}
 		c.savePage(page)
 		c.updateCache(page, time.Now())
 		return
 	}
 
-	resp, err := c.client.Do(req)
+	var trace *requestTrace
+	if c.measurePerformance {
+		req, trace = traceRequest(req)
+	}
+	sent := time.Now()
+	resp, err := c.doWithRetry(ctx, req)
+	headersAt := time.Now()
+	c.observeTLS(req, resp, err)
 	if err != nil {
+		if ctx.Err() != nil {
+			c.recordUnvisited(job.url)
+			return
+		}
+		c.recordStatsVisit()
 		errMsg := err.Error()
 		c.recordError(Error{Source: job.url, Target: job.url, Type: "request", Message: errMsg})
-		page := &PageReport{URL: job.url, Error: errMsg}
+		page := &PageReport{URL: job.url, Depth: job.depth, Error: errMsg}
 		c.savePage(page)
 		c.updateCache(page, time.Now())
 		return
 	}
 	defer resp.Body.Close()
 
-	reader := io.LimitReader(resp.Body, 5*1024*1024)
-	body, err := io.ReadAll(reader)
+	limit := maxPageSize
+	if normalizeMediaType(resp.Header.Get("Content-Type")) == pdfMediaType {
+		limit = maxPDFSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	size := int64(len(body))
+	truncated := size > limit
+	if truncated {
+		body = body[:limit]
+		// The page weight covers the whole response, not just the part
+		// that is parsed.
+		if resp.ContentLength > size {
+			size = resp.ContentLength
+		} else {
+			rest, drainErr := io.Copy(io.Discard, resp.Body)
+			size += rest
+			if err == nil {
+				err = drainErr
+			}
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		c.recordUnvisited(job.url)
+		return
+	}
+	// Pages abandoned by cancellation are listed as unvisited instead of
+	// being counted as visited.
+	c.recordStatsVisit()
 	if err != nil {
 		errMsg := err.Error()
 		c.recordError(Error{Source: job.url, Target: job.url, Type: "read", Message: errMsg})
-		page := &PageReport{URL: job.url, Status: resp.StatusCode, Error: errMsg, Retrieved: time.Since(start)}
+		page := &PageReport{URL: job.url, Depth: job.depth, Status: resp.StatusCode, Error: errMsg, Retrieved: time.Since(start)}
 		c.savePage(page)
 		c.updateCache(page, time.Now())
 		return
 	}
+	readAt := time.Now()
 
-	links := c.extractLinks(body, job.url)
-	if target := extractMetaRefreshTarget(body); target != "" {
-		normalized := c.normalizeURL(target)
-		if normalized != "" && !linkExists(links, normalized) {
-			linkType := LinkTypeExternal
-			if parsedTarget, err := url.Parse(normalized); err == nil && strings.EqualFold(parsedTarget.Host, c.start.Host) {
-				linkType = LinkTypeInternal
+	mediaType, sniffed := responseMediaType(resp.Header, body)
+	_, parseable := c.parseableTypes[mediaType]
+	var links []Link
+	if parseable {
+		links = c.extractLinks(body, job.url)
+		if target := extractMetaRefreshTarget(body); target != "" {
+			normalized := c.normalizeURL(target)
+			if normalized != "" && !linkExists(links, normalized) {
+				linkType := LinkTypeExternal
+				if parsedTarget, err := url.Parse(normalized); err == nil && strings.EqualFold(parsedTarget.Host, c.start.Host) {
+					linkType = LinkTypeInternal
+				}
+				links = append(links, Link{URL: normalized, Type: linkType, Element: LinkElementMetaRefresh})
 			}
-			links = append(links, Link{URL: normalized, Type: linkType})
 		}
 	}
 	pageReport := &PageReport{
-		URL:       job.url,
-		Status:    resp.StatusCode,
-		Links:     links,
-		Retrieved: time.Since(start),
+		URL:         job.url,
+		Depth:       job.depth,
+		Status:      resp.StatusCode,
+		ContentType: mediaType,
+		Links:       links,
+		Retrieved:   time.Since(start),
+		Size:        size,
+	}
+	directives := pageRobotsDirectives(resp.Header, body, parseable)
+	pageReport.NoIndex, pageReport.NoFollow = directives.noindex, directives.nofollow
+	if trace != nil {
+		pageReport.Timing = trace.timing(sent, headersAt, readAt)
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		// Normalized like links, so that the structure analysis can match
+		// redirect targets against link targets and pages.
+		if final := c.normalizeURL(resp.Request.URL.String()); final != "" && final != job.url {
+			pageReport.RedirectURL = final
+		}
+	}
+	// One conversion serves soft-404 detection, the fingerprint, which every
+	// page gets, and the markdown export.
+	var markdown string
+	var convertErr error
+	if parseable {
+		markdown, convertErr = convertToMarkdown(job.url, body)
 	}
 	if resp.StatusCode >= 400 {
 		msg := fmt.Sprintf("status %d", resp.StatusCode)
 		c.recordError(Error{Source: job.url, Target: job.url, Type: "http", Message: msg, Status: resp.StatusCode})
 		pageReport.Error = msg
+	} else if parseable {
+		if msg := c.detectSoft404(ctx, pageReport, body, markdown, convertErr); msg != "" {
+			c.recordError(Error{Source: job.url, Target: job.url, Type: "soft404", Message: msg, Status: resp.StatusCode})
+			pageReport.Error = msg
+		}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		c.checkContentType(pageReport, sniffed)
+	}
+	if !parseable {
+		// Other media types are only checked for their status, except for
+		// the link annotations of PDF documents.
+		if mediaType == pdfMediaType && resp.StatusCode < 400 {
+			base := job.url
+			if pageReport.RedirectURL != "" {
+				base = pageReport.RedirectURL
+			}
+			if truncated {
+				msg := fmt.Sprintf("document larger than %d bytes; link annotations past that size are not checked", limit)
+				c.recordError(Error{Source: job.url, Target: job.url, Type: "pdf", Message: msg, Status: resp.StatusCode})
+			}
+			pdfLinks, err := c.extractPDFLinks(body, base)
+			if err != nil {
+				c.recordError(Error{Source: job.url, Target: job.url, Type: "pdf", Message: err.Error(), Status: resp.StatusCode})
+			}
+			pageReport.Links = pdfLinks
+			c.followLinks(ctx, job, pageReport, pdfLinks)
+		}
+		if c.markdownDir != "" {
+			pageReport.MarkdownSkippedReason = "content type " + mediaType
+		}
+		c.savePage(pageReport)
+		c.updateCache(pageReport, time.Now())
+		return
 	}
 
+	if c.auditHeaders && resp.StatusCode < 400 {
+		pageReport.Headers = extractSecurityHeaders(resp)
+	}
+	if c.checkMixed && resp.StatusCode < 400 {
+		c.checkMixedContent(ctx, pageReport, body, links)
+	}
+	if c.followCSS && resp.StatusCode < 400 {
+		base := job.url
+		if pageReport.RedirectURL != "" {
+			base = pageReport.RedirectURL
+		}
+		c.checkCSS(ctx, pageReport, base, body)
+	}
+
+	c.followLinks(ctx, job, pageReport, links)
+	if c.relations && resp.StatusCode < 400 {
+		base := job.url
+		if pageReport.RedirectURL != "" {
+			base = pageReport.RedirectURL
+		}
+		relations, relationLinks := c.extractRelations(body, base)
+		pageReport.Relations = relations
+		c.followRelations(ctx, job, pageReport, relationLinks)
+	}
+
+	if c.auditSEO {
+		base := job.url
+		if pageReport.RedirectURL != "" {
+			base = pageReport.RedirectURL
+		}
+		pageReport.SEO = c.extractSEOMetadata(body, base)
+		if baseURL, err := url.Parse(base); err == nil && !c.relations {
+			// Crawl the canonical target so that AuditSEO can check its
+			// status; CheckRelations already follows it.
+			if canonical := c.extractCanonical(maskHiddenMarkup(body), baseURL); canonical != nil {
+				c.followRelations(ctx, job, pageReport, []Link{*canonical})
+			}
+		}
+	}
+	if pageReport.Error == "" && resp.StatusCode < 300 {
+		c.runChecks(pageReport, body)
+	}
+	var text string
+	if convertErr == nil {
+		text = c.pageText(job.url, markdown, body)
+		c.fingerprintContent(pageReport, text)
+	}
+	visitedAt := time.Now()
+	c.writeMarkdown(pageReport, text, convertErr, visitedAt)
+	c.savePage(pageReport)
+	c.updateCache(pageReport, visitedAt)
+}
+
+// followLinks records the links found on a page and enqueues their targets,
+// except for links withheld by nofollow directives.
+func (c *crawler) followLinks(ctx context.Context, job internalJob, page *PageReport, links []Link) {
+	c.recordReferrers(job.url, links)
 	for _, link := range links {
 		switch link.Type {
 		case LinkTypeInternal:
 			c.recordInternalLink()
-			c.enqueueInternal(link.URL, job.depth+1)
+			if !c.withheldLink(page, link) {
+				c.enqueueInternal(ctx, link.URL, job.depth+1)
+			}
 		case LinkTypeExternal:
 			c.recordExternalLink()
-			if c.allowExternal {
-				c.enqueueExternal(link.URL, job.url)
+			if c.allowExternal && !c.withheldLink(page, link) {
+				c.enqueueExternal(ctx, link.URL, job.url, &link)
 			}
 		}
 	}
-
-	visitedAt := time.Now()
-	c.writeMarkdown(pageReport, body, visitedAt)
-	c.savePage(pageReport)
-	c.updateCache(pageReport, visitedAt)
 }
 
 func linkExists(links []Link, target string) bool {
@@ -121,34 +281,75 @@ func (c *crawler) processExternal(ctx context.Context, job externalJob) {
 	c.emitProgress(job.url)
 	parsed, err := url.Parse(job.url)
 	if err != nil {
-		c.recordError(Error{Source: job.source, Target: job.url, Type: "parse", Message: err.Error()})
+		c.recordError(Error{Source: job.source, Target: job.url, Type: "parse", Message: err.Error(), Link: job.via})
 		return
 	}
 	if !c.allowedByRobots(ctx, parsed) {
-		c.recordSkippedRobots()
+		c.recordSkippedRobots(job.url)
 		return
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.url, nil)
 	if err != nil {
-		c.recordError(Error{Source: job.source, Target: job.url, Type: "request", Message: err.Error()})
+		c.recordError(Error{Source: job.source, Target: job.url, Type: "request", Message: err.Error(), Link: job.via})
 		return
 	}
 	req.Header.Set("User-Agent", defaultUserAgent)
 	if !c.acquireRequestSlot(ctx) {
-		c.recordError(Error{Source: job.source, Target: job.url, Type: "rate", Message: "rate limit reached"})
+		if ctx.Err() != nil {
+			c.recordUnvisited(job.url)
+			return
+		}
+		c.recordError(Error{Source: job.source, Target: job.url, Type: "rate", Message: "rate limit reached", Link: job.via})
 		return
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.doWithRetry(ctx, req)
+	c.observeTLS(req, resp, err)
 	if err != nil {
-		c.recordError(Error{Source: job.source, Target: job.url, Type: "request", Message: err.Error()})
+		if ctx.Err() != nil {
+			c.recordUnvisited(job.url)
+			return
+		}
+		c.recordError(Error{Source: job.source, Target: job.url, Type: "request", Message: err.Error(), Link: job.via})
 		return
 	}
 	resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		c.recordError(Error{Source: job.source, Target: job.url, Type: "http", Message: fmt.Sprintf("status %d", resp.StatusCode), Status: resp.StatusCode})
+		c.recordError(Error{Source: job.source, Target: job.url, Type: "http", Message: fmt.Sprintf("status %d", resp.StatusCode), Status: resp.StatusCode, Link: job.via})
+	}
+
+	if !job.asset {
+		c.recordExternalChecked()
 	}
+}
 
-	c.recordExternalChecked()
+// retryBackoff is the delay before the first retry; later retries wait
+// proportionally longer.
+var retryBackoff = 500 * time.Millisecond
+
+// doWithRetry sends req and retries transport errors and 5xx responses up to
+// c.retries times. Every retry waits for the backoff and a rate limiter slot.
+func (c *crawler) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if attempt >= c.retries || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryBackoff * time.Duration(attempt+1)):
+		}
+		if !c.acquireRequestSlot(ctx) {
+			return nil, ctx.Err()
+		}
+	}
 }
@@ -26,13 +26,16 @@ func (c *crawler) setupRateLimiter(ctx context.Context, maxWorkers int) {
 	for i := 0; i < fill; i++ {
 		c.rateLimiter <- struct{}{}
 	}
-	c.rateTicker = time.NewTicker(interval)
+	// The goroutine keeps its own reference, because shutdownRateLimiter
+	// clears c.rateTicker while the goroutine may still be selecting on it.
+	ticker := time.NewTicker(interval)
+	c.rateTicker = ticker
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-c.rateTicker.C:
+			case <-ticker.C:
 				select {
 				case c.rateLimiter <- struct{}{}:
 				default:
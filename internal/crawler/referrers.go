@@ -0,0 +1,85 @@
+package crawler
+
+import "sort"
+
+// brokenTargetTypes lists the error types that mean the target itself could
+// not be retrieved, as opposed to failures while processing a fetched page.
+var brokenTargetTypes = map[string]struct{}{
+	"http":    {},
+	"request": {},
+	"read":    {},
+	"parse":   {},
+	"soft404": {},
+}
+
+func (c *crawler) recordReferrers(source string, links []Link) {
+	c.reportMu.Lock()
+	for _, link := range links {
+		c.referrers[link.URL] = append(c.referrers[link.URL], Referrer{
+			Source: source,
+			Text:   link.Text,
+			Line:   link.Line,
+		})
+	}
+	c.reportMu.Unlock()
+}
+
+func (c *crawler) collectReferrers() map[string][]Referrer {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	for _, refs := range c.referrers {
+		sortReferrers(refs)
+	}
+	return c.referrers
+}
+
+func sortReferrers(refs []Referrer) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Source != refs[j].Source {
+			return refs[i].Source < refs[j].Source
+		}
+		return refs[i].Line < refs[j].Line
+	})
+}
+
+// BrokenTargets returns one entry per URL that failed validation together
+// with every page that links to it, sorted by URL. When the same target
+// failed more than once, the first recorded failure describes it.
+func (r *Report) BrokenTargets() []BrokenTarget {
+	if r == nil {
+		return nil
+	}
+	index := make(map[string]int)
+	var targets []BrokenTarget
+	for _, err := range r.Errors {
+		if _, ok := brokenTargetTypes[err.Type]; !ok {
+			continue
+		}
+		if _, ok := index[err.Target]; ok {
+			continue
+		}
+		index[err.Target] = len(targets)
+		targets = append(targets, BrokenTarget{
+			URL:     err.Target,
+			Type:    err.Type,
+			Message: err.Message,
+			Status:  err.Status,
+		})
+	}
+	for i := range targets {
+		refs := append([]Referrer(nil), r.Referrers[targets[i].URL]...)
+		if len(refs) == 0 {
+			// Fall back to the error sources when no link was recorded, for
+			// example for the start URL or reports built by hand.
+			for _, err := range r.Errors {
+				if err.Target == targets[i].URL && err.Source != err.Target {
+					refs = append(refs, Referrer{Source: err.Source})
+				}
+			}
+		}
+		sortReferrers(refs)
+		targets[i].Referrers = refs
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].URL < targets[j].URL })
+	return targets
+}
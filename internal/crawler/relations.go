@@ -0,0 +1,275 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// CheckHreflang names the findings of AuditHreflang.
+const CheckHreflang = "hreflang"
+
+// pageRelations lists the <link> relations whose targets are pages of their
+// own, in the order they are stored in LinkRelations.
+var pageRelations = []string{"canonical", "alternate", "next", "prev", "amphtml"}
+
+// hreflangPattern matches a language code with optional script and region
+// subtags, such as "de", "zh-Hant" or "es-419".
+var hreflangPattern = regexp.MustCompile(`(?i)^[a-z]{2,3}(?:-[a-z]{4})?(?:-(?:[a-z]{2}|[0-9]{3}))?$`)
+
+// extractRelations reads the link relations and srcset candidates of an HTML
+// page. Targets are resolved against base and normalized like links. The
+// returned links describe every target once per occurrence, for validation.
+func (c *crawler) extractRelations(body []byte, base string) (*LinkRelations, []Link) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, nil
+	}
+	resolve := func(raw string, element string, rel []string, line int) *Link {
+		return c.resolveRelation(baseURL, raw, element, rel, line)
+	}
+
+	relations := &LinkRelations{}
+	var links []Link
+	seenImages := map[string]struct{}{}
+	masked := maskHiddenMarkup(body)
+	if canonical := c.extractCanonical(masked, baseURL); canonical != nil {
+		relations.Canonical = canonical.URL
+		links = append(links, *canonical)
+	}
+	lines := newLineIndex(body)
+	for _, m := range startTagPattern.FindAllSubmatchIndex(masked, -1) {
+		name := strings.ToLower(string(masked[m[2]:m[3]]))
+		line := lines.line(m[0])
+		switch name {
+		case "link":
+			attrs := parseTagAttributes(masked[m[4]:m[5]])
+			rel := strings.Fields(strings.ToLower(attrs["rel"]))
+			for _, token := range pageRelations {
+				if token == "canonical" || !hasToken(attrs["rel"], token) {
+					continue
+				}
+				if token == "alternate" && attrs["hreflang"] == "" {
+					// Feeds and other alternate formats are not page
+					// relations.
+					continue
+				}
+				link := resolve(attrs["href"], LinkElementLinkRel, rel, line)
+				if link == nil {
+					continue
+				}
+				switch token {
+				case "alternate":
+					relations.Alternates = append(relations.Alternates, Alternate{Hreflang: attrs["hreflang"], URL: link.URL, Line: line})
+				case "next":
+					if relations.Next == "" {
+						relations.Next = link.URL
+					}
+				case "prev":
+					if relations.Prev == "" {
+						relations.Prev = link.URL
+					}
+				case "amphtml":
+					if relations.AMPHTML == "" {
+						relations.AMPHTML = link.URL
+					}
+				}
+				links = append(links, *link)
+				break
+			}
+		case "img", "source":
+			attrs := parseTagAttributes(masked[m[4]:m[5]])
+			for _, candidate := range parseSrcset(attrs["srcset"]) {
+				link := resolve(candidate, LinkElementSrcset, nil, line)
+				if link == nil {
+					continue
+				}
+				if _, seen := seenImages[link.URL]; !seen {
+					seenImages[link.URL] = struct{}{}
+					relations.Srcset = append(relations.Srcset, link.URL)
+				}
+				links = append(links, *link)
+			}
+		}
+	}
+	if relations.Canonical == "" && len(relations.Alternates) == 0 && relations.Next == "" && relations.Prev == "" && relations.AMPHTML == "" && len(relations.Srcset) == 0 {
+		return nil, nil
+	}
+	return relations, links
+}
+
+// extractCanonical returns the first <link rel="canonical"> of a page whose
+// hidden markup has been masked, resolved against base. Both the SEO metadata
+// and the link relations take the canonical URL from here.
+func (c *crawler) extractCanonical(masked []byte, base *url.URL) *Link {
+	for _, m := range startTagPattern.FindAllSubmatchIndex(masked, -1) {
+		if !strings.EqualFold(string(masked[m[2]:m[3]]), "link") {
+			continue
+		}
+		attrs := parseTagAttributes(masked[m[4]:m[5]])
+		if !hasToken(attrs["rel"], "canonical") {
+			continue
+		}
+		rel := strings.Fields(strings.ToLower(attrs["rel"]))
+		if link := c.resolveRelation(base, attrs["href"], LinkElementLinkRel, rel, newLineIndex(masked).line(m[0])); link != nil {
+			return link
+		}
+	}
+	return nil
+}
+
+// resolveRelation resolves a relation target against base and normalizes it
+// like a link. Targets that are not http or https URLs yield nil.
+func (c *crawler) resolveRelation(base *url.URL, raw string, element string, rel []string, line int) *Link {
+	ref, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || raw == "" {
+		return nil
+	}
+	target := base.ResolveReference(ref)
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil
+	}
+	target.Fragment = ""
+	normalized := c.normalizeURL(target.String())
+	if normalized == "" {
+		return nil
+	}
+	linkType := LinkTypeExternal
+	if strings.EqualFold(target.Host, c.start.Host) {
+		linkType = LinkTypeInternal
+	}
+	return &Link{URL: normalized, Type: linkType, Element: element, Rel: rel, Line: line}
+}
+
+// parseSrcset returns the URLs of the image candidates in a srcset attribute,
+// following the HTML parsing rules: candidates are separated by commas, and a
+// URL ends at whitespace or, when no descriptor follows, at trailing commas.
+func parseSrcset(srcset string) []string {
+	var urls []string
+	for i := 0; i < len(srcset); {
+		for i < len(srcset) && (isSrcsetSpace(srcset[i]) || srcset[i] == ',') {
+			i++
+		}
+		start := i
+		for i < len(srcset) && !isSrcsetSpace(srcset[i]) {
+			i++
+		}
+		candidate := srcset[start:i]
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			candidate = trimmed
+		} else {
+			// Skip the descriptors up to the next comma outside
+			// parentheses.
+			depth := 0
+			for ; i < len(srcset); i++ {
+				switch srcset[i] {
+				case '(':
+					depth++
+				case ')':
+					if depth > 0 {
+						depth--
+					}
+				}
+				if srcset[i] == ',' && depth == 0 {
+					break
+				}
+			}
+		}
+		if candidate != "" && !strings.HasPrefix(strings.ToLower(candidate), "data:") {
+			urls = append(urls, candidate)
+		}
+	}
+	return urls
+}
+
+func isSrcsetSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// followRelations validates the targets of a page's link relations. Internal
+// page relations are crawled, so that AuditHreflang sees both ends of an
+// alternate pair; srcset images are only checked for their status. External
+// targets are checked when Config.AllowExternal is set.
+func (c *crawler) followRelations(ctx context.Context, job internalJob, page *PageReport, links []Link) {
+	c.recordReferrers(job.url, links)
+	for _, link := range links {
+		switch {
+		case c.withheldLink(page, link):
+		case link.Element == LinkElementSrcset && link.Type == LinkTypeInternal:
+			c.enqueueStatusCheck(ctx, externalJob{url: link.URL, source: job.url, via: &link, asset: true})
+		case link.Type == LinkTypeInternal:
+			c.enqueueInternal(ctx, link.URL, job.depth+1)
+		case c.allowExternal:
+			c.enqueueExternal(ctx, link.URL, job.url, &link)
+		}
+	}
+}
+
+// AuditHreflang checks the hreflang alternates recorded on successfully
+// crawled pages. It reports invalid language codes and alternates whose
+// target was crawled but does not list the page as an alternate in return.
+// Targets that were not crawled are not checked. Findings are sorted by URL
+// and rule.
+func AuditHreflang(r *Report) []Finding {
+	if r == nil {
+		return nil
+	}
+	crawled := func(page *PageReport) bool {
+		return page != nil && page.Relations != nil && page.Error == "" && page.Status >= 200 && page.Status < 300
+	}
+	var findings []Finding
+	for _, key := range sortedPageKeys(r.Pages) {
+		page := r.Pages[key]
+		if !crawled(page) {
+			continue
+		}
+		for _, alt := range page.Relations.Alternates {
+			if !strings.EqualFold(alt.Hreflang, "x-default") && !hreflangPattern.MatchString(alt.Hreflang) {
+				findings = append(findings, Finding{
+					Check:    CheckHreflang,
+					Rule:     "hreflang-invalid",
+					URL:      page.URL,
+					Message:  fmt.Sprintf("hreflang %q is not a valid language code", alt.Hreflang),
+					Snippet:  alt.URL,
+					Line:     alt.Line,
+					Severity: SeverityWarning,
+				})
+			}
+			target := r.Pages[alt.URL]
+			if !crawled(target) || finalURL(target) == finalURL(page) {
+				continue
+			}
+			if !listsAlternate(target.Relations, page) {
+				findings = append(findings, Finding{
+					Check:    CheckHreflang,
+					Rule:     "hreflang-not-reciprocal",
+					URL:      page.URL,
+					Message:  fmt.Sprintf("alternate for %q does not link back to this page", alt.Hreflang),
+					Snippet:  alt.URL,
+					Line:     alt.Line,
+					Severity: SeverityWarning,
+				})
+			}
+		}
+	}
+	sortFindings(findings)
+	return findings
+}
+
+func listsAlternate(relations *LinkRelations, page *PageReport) bool {
+	for _, alt := range relations.Alternates {
+		if alt.URL == page.URL || alt.URL == finalURL(page) {
+			return true
+		}
+	}
+	return false
+}
+
+func finalURL(page *PageReport) string {
+	if page.RedirectURL != "" {
+		return page.RedirectURL
+	}
+	return page.URL
+}
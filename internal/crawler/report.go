@@ -6,11 +6,17 @@ func (c *crawler) recordError(err Error) {
 	c.reportMu.Unlock()
 }
 
+func (c *crawler) recordUnvisited(u string) {
+	c.reportMu.Lock()
+	c.unvisited = append(c.unvisited, u)
+	c.reportMu.Unlock()
+}
+
 func (c *crawler) savePage(page *PageReport) {
-    c.reportMu.Lock()
-    if existing, ok := c.pages[page.URL]; ok {
-        if page.Error != "" {
-            existing.Error = page.Error
+	c.reportMu.Lock()
+	if existing, ok := c.pages[page.URL]; ok {
+		if page.Error != "" {
+			existing.Error = page.Error
 		}
 		if len(page.Links) > 0 {
 			existing.Links = page.Links
@@ -18,17 +24,29 @@ func (c *crawler) savePage(page *PageReport) {
 		if page.Status != 0 {
 			existing.Status = page.Status
 		}
-        if page.Retrieved != 0 {
-            existing.Retrieved = page.Retrieved
-        }
-        if page.MarkdownPath != "" {
-            existing.MarkdownPath = page.MarkdownPath
-            existing.MarkdownSkippedReason = ""
-        } else if page.MarkdownSkippedReason != "" && existing.MarkdownPath == "" {
-            existing.MarkdownSkippedReason = page.MarkdownSkippedReason
-        }
-    } else {
-        c.pages[page.URL] = page
-    }
-    c.reportMu.Unlock()
+		if page.RedirectURL != "" {
+			existing.RedirectURL = page.RedirectURL
+		}
+		if page.Retrieved != 0 {
+			existing.Retrieved = page.Retrieved
+		}
+		if page.SEO != nil {
+			existing.SEO = page.SEO
+		}
+		existing.NoIndex = existing.NoIndex || page.NoIndex
+		existing.NoFollow = existing.NoFollow || page.NoFollow
+		if page.ContentHash != "" {
+			existing.ContentHash = page.ContentHash
+			existing.SimHash = page.SimHash
+		}
+		if page.MarkdownPath != "" {
+			existing.MarkdownPath = page.MarkdownPath
+			existing.MarkdownSkippedReason = ""
+		} else if page.MarkdownSkippedReason != "" && existing.MarkdownPath == "" {
+			existing.MarkdownSkippedReason = page.MarkdownSkippedReason
+		}
+	} else {
+		c.pages[page.URL] = page
+	}
+	c.reportMu.Unlock()
 }
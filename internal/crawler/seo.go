@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length limits beyond which search engines usually truncate titles and
+// descriptions in result pages.
+const (
+	maxTitleLength       = 60
+	maxDescriptionLength = 160
+)
+
+var (
+	metaTagPattern = regexp.MustCompile(`(?is)<meta\b([^>]*)>`)
+	htmlTagPattern = regexp.MustCompile(`(?is)<html\b([^>]*)>`)
+	h1Pattern      = regexp.MustCompile(`(?i)<h1[\s>]`)
+)
+
+// extractSEOMetadata reads the search-relevant metadata of an HTML page. The
+// canonical URL comes from extractCanonical.
+func (c *crawler) extractSEOMetadata(body []byte, pageURL string) *SEOMetadata {
+	masked := maskHiddenMarkup(body)
+	src := string(masked)
+	meta := &SEOMetadata{
+		Title:   collapseUnicodeSpaces(extractHTMLTitle(src)),
+		H1Count: len(h1Pattern.FindAllStringIndex(src, -1)),
+	}
+	if m := htmlTagPattern.FindStringSubmatch(src); m != nil {
+		meta.Lang = parseTagAttributes([]byte(m[1]))["lang"]
+	}
+	for _, m := range metaTagPattern.FindAllStringSubmatch(src, -1) {
+		attrs := parseTagAttributes([]byte(m[1]))
+		content := collapseUnicodeSpaces(attrs["content"])
+		name := strings.ToLower(attrs["name"])
+		property := strings.ToLower(attrs["property"])
+		switch {
+		case name == "description" && meta.Description == "":
+			meta.Description = content
+		case strings.HasPrefix(property, "og:"):
+			if meta.OpenGraph == nil {
+				meta.OpenGraph = map[string]string{}
+			}
+			if _, exists := meta.OpenGraph[property]; !exists {
+				meta.OpenGraph[property] = content
+			}
+		}
+	}
+	if base, err := url.Parse(pageURL); err == nil {
+		if canonical := c.extractCanonical(masked, base); canonical != nil {
+			meta.Canonical = canonical.URL
+		}
+	}
+	return meta
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditSEO checks the metadata recorded on successfully crawled pages and
+// returns findings for missing, duplicate and overlong titles and
+// descriptions, missing or repeated h1 headings, and canonical URLs that
+// point elsewhere or at a page that did not answer 200. The crawler follows
+// canonical URLs on the crawled site when SEO auditing is on; targets that
+// were not crawled, such as those on other hosts, are not checked. Findings
+// are sorted by URL and rule.
+func AuditSEO(r *Report) []Finding {
+	if r == nil {
+		return nil
+	}
+	var findings []Finding
+	add := func(pageURL, rule, message, snippet string) {
+		findings = append(findings, Finding{
+			Check:    CheckSEO,
+			Rule:     rule,
+			URL:      pageURL,
+			Message:  message,
+			Snippet:  snippet,
+			Severity: SeverityWarning,
+		})
+	}
+
+	type audited struct {
+		page *PageReport
+		meta *SEOMetadata
+	}
+	var pages []audited
+	titles := map[string][]string{}
+	descriptions := map[string][]string{}
+	seenFinal := map[string]struct{}{}
+	for _, key := range sortedPageKeys(r.Pages) {
+		page := r.Pages[key]
+		if page.SEO == nil || page.Error != "" || page.Status < 200 || page.Status >= 300 {
+			continue
+		}
+		final := page.URL
+		if page.RedirectURL != "" {
+			final = page.RedirectURL
+		}
+		if _, ok := seenFinal[final]; ok {
+			continue
+		}
+		seenFinal[final] = struct{}{}
+		pages = append(pages, audited{page: page, meta: page.SEO})
+		if page.SEO.Title != "" {
+			titles[page.SEO.Title] = append(titles[page.SEO.Title], page.URL)
+		}
+		if page.SEO.Description != "" {
+			descriptions[page.SEO.Description] = append(descriptions[page.SEO.Description], page.URL)
+		}
+	}
+
+	for _, p := range pages {
+		meta := p.meta
+		u := p.page.URL
+		switch {
+		case meta.Title == "":
+			add(u, "title-missing", "page has no <title>", "")
+		case len(titles[meta.Title]) > 1:
+			add(u, "title-duplicate", fmt.Sprintf("title is shared with %s", otherPages(titles[meta.Title], u)), meta.Title)
+		}
+		if n := utf8.RuneCountInString(meta.Title); n > maxTitleLength {
+			add(u, "title-too-long", fmt.Sprintf("title has %d characters, more than %d", n, maxTitleLength), meta.Title)
+		}
+		switch {
+		case meta.Description == "":
+			add(u, "description-missing", "page has no meta description", "")
+		case len(descriptions[meta.Description]) > 1:
+			add(u, "description-duplicate", fmt.Sprintf("meta description is shared with %s", otherPages(descriptions[meta.Description], u)), meta.Description)
+		}
+		if n := utf8.RuneCountInString(meta.Description); n > maxDescriptionLength {
+			add(u, "description-too-long", fmt.Sprintf("meta description has %d characters, more than %d", n, maxDescriptionLength), meta.Description)
+		}
+		switch {
+		case meta.H1Count == 0:
+			add(u, "h1-missing", "page has no <h1> heading", "")
+		case meta.H1Count > 1:
+			add(u, "h1-multiple", fmt.Sprintf("page has %d <h1> headings", meta.H1Count), "")
+		}
+		if meta.Canonical != "" {
+			if meta.Canonical != u && meta.Canonical != p.page.RedirectURL {
+				add(u, "canonical-not-self", "canonical URL points to another page", meta.Canonical)
+			}
+			if target, ok := r.Pages[meta.Canonical]; ok && (target.Status != 200 || target.RedirectURL != "") {
+				status := fmt.Sprintf("status %d", target.Status)
+				if target.RedirectURL != "" {
+					status = "a redirect"
+				} else if target.Status == 0 {
+					status = "an error"
+				}
+				add(u, "canonical-error", fmt.Sprintf("canonical URL answered with %s", status), meta.Canonical)
+			}
+		}
+	}
+
+	sortFindings(findings)
+	return findings
+}
+
+func otherPages(urls []string, self string) string {
+	others := make([]string, 0, len(urls)-1)
+	for _, u := range urls {
+		if u != self {
+			others = append(others, u)
+		}
+	}
+	if len(others) == 1 {
+		return others[0]
+	}
+	return fmt.Sprintf("%s and %d other pages", others[0], len(others)-1)
+}
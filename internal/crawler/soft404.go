@@ -0,0 +1,189 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// soft404Fingerprint describes how a host answers a URL that cannot exist.
+// hash and simHash fingerprint the text of a 2xx response, and redirect is
+// the final URL when the probe was redirected. All are empty when the host
+// answers with a proper error status.
+type soft404Fingerprint struct {
+	hash     string
+	simHash  uint64
+	redirect string
+}
+
+// soft404Host probes a host once. Workers needing the fingerprint of the same
+// host wait for the probe, while other hosts proceed independently.
+type soft404Host struct {
+	once sync.Once
+	fp   *soft404Fingerprint
+}
+
+// soft404Pattern is a case-insensitive title or body pattern.
+type soft404Pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+func compileSoft404Patterns(patterns []string) ([]soft404Pattern, error) {
+	compiled := make([]soft404Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid soft-404 pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, soft404Pattern{source: pattern, re: re})
+	}
+	return compiled, nil
+}
+
+// detectSoft404 reports why a successful response looks like a "not found"
+// page, or returns an empty string. Title and body patterns are checked
+// first; in probe mode the page is then compared with the host's response to
+// a random nonexistent URL. markdown and convertErr are the results of
+// convertToMarkdown on body, which the caller has already run.
+func (c *crawler) detectSoft404(ctx context.Context, page *PageReport, body []byte, markdown string, convertErr error) string {
+	if len(c.soft404Title) > 0 {
+		if title := extractHTMLTitle(string(body)); title != "" {
+			for _, pattern := range c.soft404Title {
+				if pattern.re.MatchString(title) {
+					return fmt.Sprintf("soft 404: title matches %q", pattern.source)
+				}
+			}
+		}
+	}
+	if len(c.soft404Body) > 0 {
+		text := extractVisibleText(string(body))
+		for _, pattern := range c.soft404Body {
+			if pattern.re.MatchString(text) {
+				return fmt.Sprintf("soft 404: content matches %q", pattern.source)
+			}
+		}
+	}
+	if !c.soft404Probe {
+		return ""
+	}
+
+	parsed, err := url.Parse(page.URL)
+	if err != nil {
+		return ""
+	}
+	fp := c.soft404FingerprintFor(ctx, parsed)
+	if fp == nil {
+		return ""
+	}
+	if fp.redirect != "" && page.RedirectURL == fp.redirect && page.URL != c.start.String() {
+		return "soft 404: redirects to the same page as a nonexistent URL"
+	}
+	if page.RedirectURL != "" {
+		if final, err := url.Parse(page.RedirectURL); err == nil {
+			parsed = final
+		}
+	}
+	if fp.hash == "" {
+		return ""
+	}
+	hash, sim := soft404Hashes(parsed, markdown, convertErr, body)
+	// Short texts have no SimHash and must match exactly.
+	if hash == fp.hash || sim != 0 && fp.simHash != 0 && bits.OnesCount64(sim^fp.simHash) <= DefaultNearDuplicateDistance {
+		return "soft 404: content matches the response for a nonexistent URL"
+	}
+	return ""
+}
+
+// soft404FingerprintFor returns the fingerprint of u's host, probing the host
+// on first use. The global lock only guards the map, never the probe.
+func (c *crawler) soft404FingerprintFor(ctx context.Context, u *url.URL) *soft404Fingerprint {
+	host := strings.ToLower(u.Host)
+	c.soft404Mu.Lock()
+	entry, ok := c.soft404Fingerprints[host]
+	if !ok {
+		entry = &soft404Host{}
+		c.soft404Fingerprints[host] = entry
+	}
+	c.soft404Mu.Unlock()
+	entry.once.Do(func() { entry.fp = c.probeSoft404(ctx, u) })
+	return entry.fp
+}
+
+// probeSoft404 requests a random path on u's host and fingerprints the answer.
+// Hosts whose robots.txt disallows the probe path are not probed.
+func (c *crawler) probeSoft404(ctx context.Context, u *url.URL) *soft404Fingerprint {
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+	probeURL := &url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   "/linkcheck-" + hex.EncodeToString(token),
+	}
+	if !c.allowedByRobots(ctx, probeURL) {
+		return nil
+	}
+	if !c.acquireRequestSlot(ctx) {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	fp := &soft404Fingerprint{}
+	if resp.StatusCode >= 300 {
+		return fp
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		if final := resp.Request.URL.String(); final != probeURL.String() {
+			fp.redirect = final
+			return fp
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5*1024*1024))
+	if err != nil {
+		return nil
+	}
+	markdown, convertErr := convertToMarkdown(probeURL.String(), body)
+	fp.hash, fp.simHash = soft404Hashes(probeURL, markdown, convertErr, body)
+	return fp
+}
+
+// soft404Hashes returns the content hash and SimHash of a response,
+// computed like PageReport.ContentHash and SimHash but from the markdown
+// before boilerplate removal and with the requested URL and path replaced.
+// The probe is not a page of the site, so it cannot take part in boilerplate
+// detection, and "not found" templates echo the requested address, which
+// would otherwise make every such page differ from the probe.
+func soft404Hashes(u *url.URL, markdown string, convertErr error, body []byte) (string, uint64) {
+	text := markdown
+	if convertErr != nil || text == "" {
+		text = buildEmptyContentFallback(body)
+	}
+	replacements := []string{u.String(), "{url}"}
+	for _, p := range []string{u.EscapedPath(), u.Path, strings.TrimPrefix(u.Path, "/")} {
+		if p != "" && p != "/" {
+			replacements = append(replacements, p, "{path}")
+		}
+	}
+	text = strings.NewReplacer(replacements...).Replace(text)
+	return contentHash(text), simHash(text)
+}
@@ -1,6 +1,9 @@
 package crawler
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 func (c *crawler) recordStatsVisit() {
 	c.mu.Lock()
@@ -26,36 +29,92 @@ func (c *crawler) recordExternalLink() {
 	c.mu.Unlock()
 }
 
-func (c *crawler) recordSkippedCache() {
+func (c *crawler) recordSkippedCache(u string) {
 	c.mu.Lock()
 	c.stats.SkippedByCache++
+	c.recordSkipLocked(u, SkipReasonCache)
 	c.mu.Unlock()
 }
 
-func (c *crawler) recordSkippedRobots() {
+func (c *crawler) recordSkippedRobots(u string) {
 	c.mu.Lock()
 	c.stats.SkippedByRobots++
+	c.recordSkipLocked(u, SkipReasonRobots)
 	c.mu.Unlock()
 }
 
-func (c *crawler) recordSkippedExtension() {
+func (c *crawler) recordSkippedExtension(u string) {
 	c.mu.Lock()
 	c.stats.SkippedByExtension++
+	c.recordSkipLocked(u, SkipReasonExtension)
 	c.mu.Unlock()
 }
 
-func (c *crawler) recordSkippedLimit() {
+func (c *crawler) recordSkippedLimit(u string) {
 	c.mu.Lock()
 	c.stats.SkippedByLimit++
+	c.recordSkipLocked(u, SkipReasonLimit)
 	c.mu.Unlock()
 }
 
-func (c *crawler) recordSkippedDepth() {
+func (c *crawler) recordSkippedDepth(u string) {
 	c.mu.Lock()
 	c.stats.SkippedByDepth++
+	c.recordSkipLocked(u, SkipReasonDepth)
 	c.mu.Unlock()
 }
 
+func (c *crawler) recordSkippedNofollow(u string) {
+	c.mu.Lock()
+	c.stats.SkippedByNofollow++
+	c.recordSkipLocked(u, SkipReasonNofollow)
+	c.mu.Unlock()
+}
+
+func (c *crawler) recordSkippedRelNofollow(u string) {
+	c.mu.Lock()
+	c.stats.SkippedByRelNofollow++
+	c.recordSkipLocked(u, SkipReasonRelNofollow)
+	c.mu.Unlock()
+}
+
+// recordSkipLocked remembers the first reason a URL was skipped. Callers must
+// hold c.mu.
+func (c *crawler) recordSkipLocked(u string, reason SkipReason) {
+	if u == "" {
+		return
+	}
+	if _, ok := c.skipped[u]; ok {
+		return
+	}
+	c.skipped[u] = reason
+}
+
+// collectSkipped returns the skipped URLs sorted by URL. URLs that were
+// skipped along one path but fetched or checked along another are dropped,
+// except for robots.txt blocks which are reported as pages without being
+// fetched. It takes mu before reportMu, which guards pages.
+func (c *crawler) collectSkipped() []Skip {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	skipped := make([]Skip, 0, len(c.skipped))
+	for u, reason := range c.skipped {
+		if reason != SkipReasonRobots {
+			if _, ok := c.pages[u]; ok {
+				continue
+			}
+			if _, ok := c.visitedExternal[u]; ok {
+				continue
+			}
+		}
+		skipped = append(skipped, Skip{URL: u, Reason: reason})
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].URL < skipped[j].URL })
+	return skipped
+}
+
 func (c *crawler) collectStats(duration time.Duration) Stats {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// AnalyzeStructure derives the internal link structure of a finished crawl.
+// Redirected pages are merged with their final URL, so a link to an old URL
+// counts as a link to the page it redirects to. Depth is the shortest link
+// distance from the start URL, or -1 for pages that links never reach.
+// Dead ends are healthy pages without outbound internal links, redirect-only
+// pages are final URLs that no page links to directly, and orphans are
+// sitemap entries that no crawled page links to.
+func AnalyzeStructure(r *Report) *Structure {
+	s := &Structure{}
+	if r == nil {
+		return s
+	}
+
+	canonical := func(u string) string {
+		if page, ok := r.Pages[u]; ok && page.RedirectURL != "" {
+			return page.RedirectURL
+		}
+		return u
+	}
+
+	nodes := map[string]*PageStructure{}
+	healthy := map[string]bool{}
+	for u, page := range r.Pages {
+		if page == nil {
+			continue
+		}
+		id := canonical(u)
+		if _, ok := nodes[id]; !ok {
+			nodes[id] = &PageStructure{URL: id, Depth: -1}
+		}
+		if page.Error == "" && page.Status > 0 && page.Status < 400 {
+			healthy[id] = true
+		}
+	}
+
+	outbound := map[string]map[string]struct{}{}
+	inbound := map[string]map[string]struct{}{}
+	linkedDirectly := map[string]struct{}{}
+	for u, page := range r.Pages {
+		if page == nil {
+			continue
+		}
+		source := canonical(u)
+		for _, link := range page.Links {
+			if link.Type != LinkTypeInternal {
+				continue
+			}
+			target := canonical(link.URL)
+			if target == source {
+				continue
+			}
+			linkedDirectly[link.URL] = struct{}{}
+			if outbound[source] == nil {
+				outbound[source] = map[string]struct{}{}
+			}
+			outbound[source][target] = struct{}{}
+			if inbound[target] == nil {
+				inbound[target] = map[string]struct{}{}
+			}
+			inbound[target][source] = struct{}{}
+		}
+	}
+
+	start := canonical(r.StartURL)
+	if node, ok := nodes[start]; ok {
+		node.Depth = 0
+		queue := []string{start}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for target := range outbound[current] {
+				if next, ok := nodes[target]; ok && next.Depth < 0 {
+					next.Depth = nodes[current].Depth + 1
+					queue = append(queue, target)
+				}
+			}
+		}
+	}
+
+	for id, node := range nodes {
+		node.Inbound = len(inbound[id])
+		node.Outbound = len(outbound[id])
+		s.Pages = append(s.Pages, *node)
+		if healthy[id] && node.Outbound == 0 {
+			s.DeadEnds = append(s.DeadEnds, id)
+		}
+	}
+	for _, page := range r.Pages {
+		if page == nil || page.RedirectURL == "" || page.RedirectURL == start {
+			continue
+		}
+		if _, ok := linkedDirectly[page.RedirectURL]; !ok {
+			s.RedirectOnly = append(s.RedirectOnly, page.RedirectURL)
+		}
+	}
+	for _, entry := range r.Sitemap {
+		id := canonical(entry)
+		if id == start {
+			continue
+		}
+		if len(inbound[id]) == 0 {
+			s.Orphans = append(s.Orphans, entry)
+		}
+	}
+
+	sort.Slice(s.Pages, func(i, j int) bool { return s.Pages[i].URL < s.Pages[j].URL })
+	s.DeadEnds = sortedUnique(s.DeadEnds)
+	s.RedirectOnly = sortedUnique(s.RedirectOnly)
+	s.Orphans = sortedUnique(s.Orphans)
+	return s
+}
+
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ParseSitemap returns the page URLs of a sitemap and the child sitemaps of
+// a sitemap index. Child sitemaps are XML documents, not pages; callers fetch
+// and parse them in turn and pass only the page URLs to Config.Sitemap.
+func ParseSitemap(r io.Reader) (pages, sitemaps []string, err error) {
+	var doc struct {
+		URLs []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("parse sitemap: %w", err)
+	}
+	for _, entry := range doc.URLs {
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+	for _, entry := range doc.Sitemaps {
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+	return pages, sitemaps, nil
+}
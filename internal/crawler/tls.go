@@ -0,0 +1,209 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTLSExpiryWindow is used when Config.TLSExpiryWindow is zero.
+const defaultTLSExpiryWindow = 30 * 24 * time.Hour
+
+// CheckTLS names the findings derived from TLSHost entries.
+const CheckTLS = "tls"
+
+// tlsObservation keeps the first successful and the first failed handshake
+// seen for a host.
+type tlsObservation struct {
+	ok, failed *TLSHost
+}
+
+// observeTLS records the certificates presented along a request's redirect
+// chain, or the certificate rejected by a failed handshake.
+func (c *crawler) observeTLS(req *http.Request, resp *http.Response, err error) {
+	if !c.checkTLS {
+		return
+	}
+	if err != nil {
+		u := req.URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			if failed, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+				u = failed
+			}
+		}
+		if u.Scheme != "https" {
+			return
+		}
+		if info := tlsHostFromError(u.Hostname(), err); info != nil {
+			c.recordTLS(u, info, false)
+		}
+		return
+	}
+	// Every response along the chain carries the state of its own connection;
+	// the redirect that led to a request is linked through Request.Response.
+	for hop := resp; hop != nil && hop.Request != nil; hop = hop.Request.Response {
+		if hop.TLS != nil && hop.Request.URL != nil {
+			c.recordTLS(hop.Request.URL, tlsHostFromState(hop.Request.URL.Hostname(), hop.TLS), true)
+		}
+	}
+}
+
+func (c *crawler) recordTLS(u *url.URL, info *TLSHost, ok bool) {
+	info.Host = strings.ToLower(u.Host)
+	c.tlsMu.Lock()
+	defer c.tlsMu.Unlock()
+	obs := c.tlsHosts[info.Host]
+	if obs == nil {
+		obs = &tlsObservation{}
+		c.tlsHosts[info.Host] = obs
+	}
+	switch {
+	case ok && obs.ok == nil:
+		obs.ok = info
+	case !ok && obs.failed == nil:
+		obs.failed = info
+	}
+}
+
+// merged combines the observations of a host. Certificate details come from
+// a successful handshake when there was one and Error from a failed one; the
+// problems seen in either are kept, so neither masks the other.
+func (obs *tlsObservation) merged() TLSHost {
+	switch {
+	case obs.ok == nil:
+		return *obs.failed
+	case obs.failed == nil:
+		return *obs.ok
+	}
+	info := *obs.ok
+	info.Error = obs.failed.Error
+	info.SelfSigned = info.SelfSigned || obs.failed.SelfSigned
+	info.HostnameMismatch = info.HostnameMismatch || obs.failed.HostnameMismatch
+	return info
+}
+
+func tlsHostFromState(hostname string, state *tls.ConnectionState) *TLSHost {
+	info := &TLSHost{Version: tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) > 0 {
+		fillCertificate(info, hostname, state.PeerCertificates[0], len(state.PeerCertificates))
+	}
+	info.VersionTooOld = state.Version < tls.VersionTLS12
+	return info
+}
+
+// tlsHostFromError extracts the certificate from a verification error. It
+// returns nil for errors that are not about certificates.
+func tlsHostFromError(hostname string, err error) *TLSHost {
+	var (
+		hostnameErr  x509.HostnameError
+		authorityErr x509.UnknownAuthorityError
+		invalidErr   x509.CertificateInvalidError
+		verifyErr    *tls.CertificateVerificationError
+		cert         *x509.Certificate
+		chainLength  = 1
+	)
+	switch {
+	case errors.As(err, &hostnameErr):
+		cert = hostnameErr.Certificate
+	case errors.As(err, &authorityErr):
+		cert = authorityErr.Cert
+	case errors.As(err, &invalidErr):
+		cert = invalidErr.Cert
+	default:
+		return nil
+	}
+	if errors.As(err, &verifyErr) && len(verifyErr.UnverifiedCertificates) > 0 {
+		chainLength = len(verifyErr.UnverifiedCertificates)
+	}
+	info := &TLSHost{Error: tlsErrorMessage(err)}
+	if cert != nil {
+		fillCertificate(info, hostname, cert, chainLength)
+	}
+	return info
+}
+
+func tlsErrorMessage(err error) string {
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return verifyErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func fillCertificate(info *TLSHost, hostname string, cert *x509.Certificate, chainLength int) {
+	info.Subject = cert.Subject.String()
+	info.Issuer = cert.Issuer.String()
+	info.NotAfter = cert.NotAfter
+	info.DNSNames = append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.DNSNames = append(info.DNSNames, ip.String())
+	}
+	info.HostnameMismatch = cert.VerifyHostname(hostname) != nil
+	info.SelfSigned = chainLength == 1 && bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func (c *crawler) collectTLS() []TLSHost {
+	c.tlsMu.Lock()
+	defer c.tlsMu.Unlock()
+	if len(c.tlsHosts) == 0 {
+		return nil
+	}
+	hosts := make([]TLSHost, 0, len(c.tlsHosts))
+	for _, obs := range c.tlsHosts {
+		hosts = append(hosts, obs.merged())
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// tlsFindings reports expired and soon expiring certificates, hostname
+// mismatches, self-signed certificates and protocol versions below TLS 1.2.
+func tlsFindings(hosts []TLSHost, now time.Time, window time.Duration) []Finding {
+	var findings []Finding
+	for _, h := range hosts {
+		add := func(rule, message string, severity Severity) {
+			findings = append(findings, Finding{
+				Check:    CheckTLS,
+				Rule:     rule,
+				URL:      "https://" + h.Host + "/",
+				Message:  message,
+				Snippet:  h.Subject,
+				Severity: severity,
+			})
+		}
+		if !h.NotAfter.IsZero() {
+			switch remaining := h.NotAfter.Sub(now); {
+			case remaining <= 0:
+				add("certificate-expired", fmt.Sprintf("certificate expired on %s", h.NotAfter.UTC().Format("2006-01-02")), SeverityError)
+			case remaining <= window:
+				add("certificate-expiring", fmt.Sprintf("certificate expires on %s, in %d days", h.NotAfter.UTC().Format("2006-01-02"), int(remaining.Hours()/24)), SeverityWarning)
+			}
+		}
+		if h.HostnameMismatch {
+			add("hostname-mismatch", fmt.Sprintf("certificate is not valid for %s; it covers %s", hostOnly(h.Host), strings.Join(h.DNSNames, ", ")), SeverityError)
+		}
+		if h.SelfSigned {
+			add("self-signed", "certificate is self-signed", SeverityError)
+		}
+		if h.VersionTooOld {
+			add("protocol-version", fmt.Sprintf("server negotiated %s; TLS 1.2 or newer is required", h.Version), SeverityWarning)
+		}
+	}
+	return findings
+}
+
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
@@ -2,6 +2,7 @@ package crawler
 
 import (
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -19,35 +20,428 @@ type Config struct {
 	RequestsPerMinute int
 	AllowedExtensions []string
 	IgnoreRobots      bool
+	// IgnoreMetaRobots follows the links of pages marked nofollow by a
+	// <meta name="robots"> tag or an X-Robots-Tag header, as IgnoreRobots
+	// does. Withheld links are reported in Report.Skipped.
+	IgnoreMetaRobots bool
+	// SkipNofollowLinks leaves anchors marked rel="nofollow" unfollowed.
+	SkipNofollowLinks bool
 	CachePath         string
 	MarkdownDir       string
-	Progress          func(string)
+	// MaxDuration bounds the whole crawl. When it elapses, pending jobs are
+	// abandoned and a partial report is returned.
+	MaxDuration time.Duration
+	// Retries is the number of extra attempts for requests that fail or
+	// return a 5xx status.
+	Retries int
+	// Sitemap lists page URLs from the site's sitemap. Internal entries are
+	// crawled as additional seeds and checked for orphan pages. Like the
+	// start URL, seeds have depth 0, so MaxDepth limits the links followed
+	// from each seed.
+	Sitemap []string
+	// ParseableTypes lists the media types of internal pages that are
+	// scanned for links, audited and exported, text/html and
+	// application/xhtml+xml by default. Other responses are only checked for
+	// their status. The media type is sniffed when the Content-Type header
+	// is missing. Successful responses without a Content-Type header, or with
+	// one that contradicts the file extension, are reported as "content-type"
+	// errors.
+	ParseableTypes []string
+	Progress       func(string)
+
+	// Soft404TitlePatterns and Soft404BodyPatterns are case-insensitive
+	// regular expressions. Successful pages whose title or visible text
+	// matches one are reported as "soft404" errors.
+	Soft404TitlePatterns []string
+	Soft404BodyPatterns  []string
+	// Soft404Probe requests a random nonexistent URL per host, unless
+	// robots.txt disallows it, and flags pages whose text closely matches
+	// that response or that share its redirect target.
+	Soft404Probe bool
+
+	// AuditSEO records the title, description, canonical URL, h1 count,
+	// language and Open Graph tags of every page and adds the findings of
+	// AuditSEO to the report.
+	AuditSEO bool
+	// AuditAccessibility adds AccessibilityCheck to Checks.
+	AuditAccessibility bool
+	// Checks run on the body of every successfully fetched internal page.
+	Checks []PageCheck
+	// CheckMixedContent reports scripts, frames, stylesheets and media loaded
+	// over http by https pages as "mixed-content" errors, and http links to
+	// the crawled host as "insecure-link" errors.
+	CheckMixedContent bool
+	// ProbeHTTPS notes whether each mixed-content URL is also served over
+	// https.
+	ProbeHTTPS bool
+	// CheckCSS fetches the same-site stylesheets linked from pages and checks
+	// the resources they reference through url() and @import, with the
+	// stylesheet as the error source. References in <style> blocks and style
+	// attributes are checked with the page as source. External resources are
+	// only checked when AllowExternal is set.
+	CheckCSS bool
+	// CheckRelations records the canonical, hreflang alternate, next, prev
+	// and amphtml link relations and the srcset image candidates of every
+	// page and validates their targets. Internal relation targets are crawled
+	// like links; srcset images are only checked for their status.
+	// AuditHreflang is added to the findings.
+	CheckRelations bool
+	// CheckTLS records the certificate and protocol version of every https
+	// host contacted in Report.TLS and reports problems as findings.
+	CheckTLS bool
+	// TLSExpiryWindow reports certificates expiring within it, 30 days by
+	// default.
+	TLSExpiryWindow time.Duration
+
+	// AuditSecurityHeaders records the security headers and cookies of every
+	// internal page below status 400 and adds the results of
+	// AuditSecurityHeaders to the report.
+	AuditSecurityHeaders bool
+	// SecurityHeaderPrefixDepth is the number of path segments by which
+	// security header results are grouped.
+	SecurityHeaderPrefixDepth int
+
+	// MeasurePerformance traces DNS, connect, TLS, time to first byte and
+	// download time of every internal page and adds the results of
+	// AuditPerformance to the report.
+	MeasurePerformance bool
+	// PerformancePrefixDepth is the number of path segments by which
+	// performance results are grouped.
+	PerformancePrefixDepth int
+	// TTFBBudget and PageWeightBudget, in bytes, report slower or larger
+	// pages as findings. Setting either implies MeasurePerformance.
+	TTFBBudget       time.Duration
+	PageWeightBudget int64
 }
 
 // Report captures the outcome of a crawl.
 type Report struct {
+	StartURL   string
+	Sitemap    []string
 	Pages      map[string]*PageReport
 	Errors     []Error
 	Stats      Stats
 	StartedAt  time.Time
 	FinishedAt time.Time
+	// Partial is set when queued work was abandoned, either because the
+	// context was cancelled or because Config.MaxDuration elapsed.
+	Partial bool
+	// Cancelled distinguishes an explicit cancellation from a deadline.
+	Cancelled bool
+	// Unvisited lists, sorted, the queued URLs that were never fetched.
+	Unvisited []string
+	// Skipped lists the discovered URLs that were filtered out before
+	// fetching.
+	Skipped []Skip
+	// Referrers maps every discovered link target, internal or external, to
+	// the pages that link to it.
+	Referrers map[string][]Referrer
+	// Structure is computed by AnalyzeStructure once the crawl has finished.
+	Structure *Structure
+	// Duplicates holds the clusters found by FindDuplicates.
+	Duplicates []DuplicateCluster
+	// Findings collects the issues reported by the optional audits.
+	Findings []Finding
+	// TLS lists, sorted by host, the hosts inspected when Config.CheckTLS is
+	// set.
+	TLS []TLSHost
+	// Headers holds the security header groups computed by
+	// AuditSecurityHeaders.
+	Headers []HeaderGroup
+	// Performance holds the latency summary of AuditPerformance.
+	Performance []PerformanceGroup
+	// NoIndex lists, sorted, the pages marked noindex by a
+	// <meta name="robots"> tag or an X-Robots-Tag header.
+	NoIndex []string
+
+	// Ignored, Baselined and BaselineFixed are filled in by the baseline
+	// package when known errors are suppressed. BaselineApplied is set when
+	// the errors were compared against a baseline, so the remaining Errors
+	// are new.
+	Ignored         []IgnoredError
+	Baselined       []Error
+	BaselineFixed   []Error
+	BaselineApplied bool
+}
+
+// Structure describes the internal link graph of a crawl. Pages lists every
+// crawled page, sorted by URL, with redirected URLs merged into their target.
+// DeadEnds, RedirectOnly and Orphans hold sorted URLs.
+type Structure struct {
+	Pages        []PageStructure
+	DeadEnds     []string
+	RedirectOnly []string
+	Orphans      []string
+}
+
+// PageStructure holds the link metrics of one page. Depth is the shortest
+// link distance from the start URL, or -1 if no chain of links reaches it.
+// Inbound and Outbound count distinct internal pages linking to and from it.
+type PageStructure struct {
+	URL      string
+	Depth    int
+	Inbound  int
+	Outbound int
+}
+
+// IgnoredError records an error suppressed by an ignore rule. Baselined is set
+// when the applied baseline records the error as well.
+type IgnoredError struct {
+	Error     Error
+	Rule      string
+	Reason    string
+	Baselined bool
 }
 
 // PageReport summarizes the crawl result for one page.
 type PageReport struct {
-	URL                   string
-	Status                int
+	URL string
+	// RedirectURL holds the final URL, normalized like links, when the request
+	// was redirected.
+	RedirectURL string
+	// Depth is the number of links followed from the start URL, or from a
+	// sitemap seed, to reach the page.
+	Depth  int
+	Status int
+	// ContentType is the media type of the response, without parameters.
+	ContentType           string
 	Error                 string
 	Links                 []Link
 	Retrieved             time.Duration
 	MarkdownPath          string
 	MarkdownSkippedReason string
+	// ContentHash is the content_sha256 of the cleaned page text that the
+	// markdown export writes, and SimHash a similarity fingerprint of the
+	// same text; see FindDuplicates.
+	ContentHash string
+	SimHash     uint64
+	SEO         *SEOMetadata
+	Headers     *SecurityHeaders
+	// Size counts the bytes of the response body, including any part beyond
+	// the read limit, and Timing is set when performance is measured.
+	Size   int64
+	Timing *Timing
+	// Relations is set when Config.CheckRelations is set and the page
+	// declares any.
+	Relations *LinkRelations
+	// NoIndex and NoFollow record the robots directives addressed to the
+	// crawler.
+	NoIndex  bool
+	NoFollow bool
+}
+
+// LinkRelations holds the link relations of a page, resolved and normalized
+// like links. Srcset lists the distinct image candidates of srcset attributes
+// on <img> and <source> elements.
+type LinkRelations struct {
+	Canonical  string
+	Alternates []Alternate
+	Next       string
+	Prev       string
+	AMPHTML    string
+	Srcset     []string
+}
+
+// Alternate is a <link rel="alternate" hreflang> declaration. Line is its
+// 1-based line in the page.
+type Alternate struct {
+	Hreflang string
+	URL      string
+	Line     int
+}
+
+// SEOMetadata holds the search-relevant metadata of a page. OpenGraph maps
+// og: properties to their content.
+type SEOMetadata struct {
+	Title       string
+	Description string
+	Canonical   string
+	H1Count     int
+	Lang        string
+	OpenGraph   map[string]string
 }
 
-// Link describes a discovered link and its classification.
+// Finding is an issue reported by an optional audit rather than a broken
+// link. Check names the audit and Rule the problem within it; Snippet quotes
+// the offending value or markup when there is one and Line gives its 1-based
+// line in the page.
+type Finding struct {
+	Check    string
+	Rule     string
+	URL      string
+	Message  string
+	Snippet  string
+	Line     int
+	Severity Severity
+}
+
+// TLSHost describes the certificate a host presented. Error is set when a
+// handshake with the host failed certificate verification; when no handshake
+// succeeded, Version is unknown and the other fields describe the rejected
+// certificate. DNSNames lists the DNS and IP subject alternative names.
+type TLSHost struct {
+	Host             string
+	Version          string
+	Subject          string
+	Issuer           string
+	NotAfter         time.Time
+	DNSNames         []string
+	SelfSigned       bool
+	HostnameMismatch bool
+	VersionTooOld    bool
+	Error            string
+}
+
+// SecurityHeaders holds the security-relevant headers of a page response.
+type SecurityHeaders struct {
+	StrictTransportSecurity string
+	ContentSecurityPolicy   string
+	ContentTypeOptions      string
+	FrameOptions            string
+	ReferrerPolicy          string
+	Cookies                 []CookieFlags
+}
+
+// CookieFlags lists the attributes of a cookie set by a page. SameSite is
+// "Lax", "Strict", "None" or empty when the attribute is missing.
+type CookieFlags struct {
+	Name     string
+	Secure   bool
+	HTTPOnly bool
+	SameSite string
+}
+
+// HeaderGroup counts, for the pages of one host or path prefix, how many
+// pages break each rule of AuditSecurityHeaders.
+type HeaderGroup struct {
+	Host   string
+	Prefix string
+	Pages  int
+	Issues map[string]int
+}
+
+// Timing breaks down the retrieval of a page. DNS, Connect and TLS are zero
+// when a kept-alive connection was reused. TTFB runs from the start of the
+// request to the first response byte and Download from there to the end of
+// the body. For redirected pages it describes the final hop only;
+// PageReport.Retrieved covers the whole chain.
+type Timing struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Download time.Duration
+}
+
+// PerformanceGroup summarizes the pages of one host, or of one path prefix
+// when Prefix is set. Retrieved covers the whole retrieval of each page.
+type PerformanceGroup struct {
+	Host      string
+	Prefix    string
+	Pages     int
+	TTFB      LatencyPercentiles
+	Retrieved LatencyPercentiles
+	Size      SizePercentiles
+}
+
+// LatencyPercentiles holds nearest-rank percentiles of durations.
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// SizePercentiles holds nearest-rank percentiles of sizes in bytes.
+type SizePercentiles struct {
+	P50 int64
+	P90 int64
+	P99 int64
+}
+
+// CheckSEO names the findings of AuditSEO.
+const CheckSEO = "seo"
+
+// DuplicateCluster lists pages with identical or nearly identical content.
+// ContentHash is set for exact clusters.
+type DuplicateCluster struct {
+	Kind        DuplicateKind
+	ContentHash string
+	Pages       []string
+}
+
+// DuplicateKind distinguishes exact from near-duplicate clusters.
+type DuplicateKind string
+
+const (
+	// DuplicateExact marks pages with the same content hash.
+	DuplicateExact DuplicateKind = "exact"
+	// DuplicateNear marks pages with similar SimHashes.
+	DuplicateNear DuplicateKind = "near"
+)
+
+// Link describes a discovered link and its classification. Text holds the
+// anchor's visible text and Line the 1-based line of the anchor in the page.
+// Rel, Target and Title copy the corresponding attributes, with Rel split into
+// lower-cased tokens such as "nofollow" or "sponsored". Element names the
+// markup the link was found in. Page is the 1-based page of a link found in
+// a PDF document.
 type Link struct {
-	URL  string
-	Type LinkType
+	URL     string
+	Type    LinkType
+	Text    string
+	Line    int
+	Page    int
+	Rel     []string
+	Target  string
+	Title   string
+	Element string
+}
+
+const (
+	// LinkElementAnchor marks links found in <a href> elements.
+	LinkElementAnchor = "a"
+	// LinkElementMetaRefresh marks the target of a <meta http-equiv="refresh">.
+	LinkElementMetaRefresh = "meta-refresh"
+	// LinkElementPDF marks URI link annotations in PDF documents.
+	LinkElementPDF = "pdf-annotation"
+	// LinkElementStylesheet marks <link rel="stylesheet"> references.
+	LinkElementStylesheet = "stylesheet"
+	// LinkElementCSSImport marks @import rules in CSS.
+	LinkElementCSSImport = "css-import"
+	// LinkElementCSSURL marks url() references in stylesheets, <style>
+	// blocks and style attributes.
+	LinkElementCSSURL = "css-url"
+	// LinkElementLinkRel marks canonical, hreflang alternate, next, prev and
+	// amphtml <link> relations; Rel holds the relation.
+	LinkElementLinkRel = "link"
+	// LinkElementSrcset marks image candidates of srcset attributes.
+	LinkElementSrcset = "srcset"
+)
+
+// HasRel reports whether the link carries the given rel token.
+func (l Link) HasRel(token string) bool {
+	for _, rel := range l.Rel {
+		if strings.EqualFold(rel, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Referrer identifies a page that links to a target URL.
+type Referrer struct {
+	Source string
+	Text   string
+	Line   int
+}
+
+// BrokenTarget groups every page referring to a URL that failed validation.
+type BrokenTarget struct {
+	URL       string
+	Type      string
+	Message   string
+	Status    int
+	Referrers []Referrer
 }
 
 // LinkType describes the classification of a link.
@@ -61,14 +455,81 @@ const (
 )
 
 // Error captures a failure that occurred when visiting or validating a link.
+// Source is the page holding Link, the link that led to Target. Errors about
+// an internal page itself, whether it could not be retrieved or its content is
+// at fault, have no Link and name the page as both Source and Target, so that
+// they do not depend on which referrer was crawled first; Report.Referrers
+// lists the pages linking to it. Severity is empty until a policy classifies
+// the error.
 type Error struct {
-	Source  string
-	Target  string
-	Type    string
-	Message string
-	Status  int
+	Source   string
+	Target   string
+	Type     string
+	Message  string
+	Status   int
+	Link     *Link
+	Severity Severity
+}
+
+// Severity ranks how serious an error is.
+type Severity string
+
+const (
+	// SeverityInfo marks errors that are reported but never fail a run.
+	SeverityInfo Severity = "info"
+	// SeverityWarning marks errors worth attention that may be tolerated.
+	SeverityWarning Severity = "warning"
+	// SeverityError marks broken links that should fail a run.
+	SeverityError Severity = "error"
+)
+
+// Rank orders severities from info (1) to error (3). Unclassified errors rank
+// as errors and unknown values rank as zero.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError, "":
+		return 3
+	default:
+		return 0
+	}
 }
 
+// Internal reports whether the error concerns a page on the crawled site.
+// Errors without a link describe the start page and are internal.
+func (e Error) Internal() bool {
+	return e.Link == nil || e.Link.Type == LinkTypeInternal
+}
+
+// Skip records a discovered URL that was not fetched and the reason why.
+type Skip struct {
+	URL    string
+	Reason SkipReason
+}
+
+// SkipReason describes why a URL was not fetched.
+type SkipReason string
+
+const (
+	// SkipReasonCache indicates the URL was visited recently according to the cache.
+	SkipReasonCache SkipReason = "cache"
+	// SkipReasonRobots indicates the URL is disallowed by robots.txt.
+	SkipReasonRobots SkipReason = "robots"
+	// SkipReasonExtension indicates the URL's extension is not allowed.
+	SkipReasonExtension SkipReason = "extension"
+	// SkipReasonLimit indicates the page limit was reached.
+	SkipReasonLimit SkipReason = "limit"
+	// SkipReasonDepth indicates the URL lies beyond the maximum depth.
+	SkipReasonDepth SkipReason = "depth"
+	// SkipReasonNofollow indicates the linking page is marked nofollow.
+	SkipReasonNofollow SkipReason = "nofollow"
+	// SkipReasonRelNofollow indicates the link is marked rel="nofollow".
+	SkipReasonRelNofollow SkipReason = "rel-nofollow"
+)
+
 // Stats aggregates crawl level counters.
 type Stats struct {
 	PagesVisited         int
@@ -83,4 +544,6 @@ type Stats struct {
 	SkippedByExtension   int
 	SkippedByLimit       int
 	SkippedByDepth       int
+	SkippedByNofollow    int
+	SkippedByRelNofollow int
 }
@@ -2,36 +2,31 @@ package crawler
 
 import "context"
 
+// internalWorker consumes jobs until the queue is closed. Once the context is
+// done, remaining jobs are drained and recorded as unvisited so that Crawl
+// never blocks on work that can no longer run.
 func (c *crawler) internalWorker(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case job, ok := <-c.internalJobs:
-			if !ok {
+	for job := range c.internalJobs {
+		func() {
+			defer c.internalWG.Done()
+			if ctx.Err() != nil {
+				c.recordUnvisited(job.url)
 				return
 			}
-			func() {
-				defer c.internalWG.Done()
-				c.processInternal(ctx, job)
-			}()
-		}
+			c.processInternal(ctx, job)
+		}()
 	}
 }
 
 func (c *crawler) externalWorker(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case job, ok := <-c.externalJobs:
-			if !ok {
+	for job := range c.externalJobs {
+		func() {
+			defer c.externalWG.Done()
+			if ctx.Err() != nil {
+				c.recordUnvisited(job.url)
 				return
 			}
-			func() {
-				defer c.externalWG.Done()
-				c.processExternal(ctx, job)
-			}()
-		}
+			c.processExternal(ctx, job)
+		}()
 	}
 }
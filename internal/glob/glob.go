@@ -0,0 +1,27 @@
+// Package glob compiles the URL and host globs used by ignore files and
+// severity policies. "*" matches any run of characters and "?" a single
+// character; everything else matches literally and the whole input must match.
+package glob
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Compile returns a regular expression equivalent to pattern.
+func Compile(pattern string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
@@ -0,0 +1,27 @@
+package glob
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"https://legacy.example.com/*", "https://legacy.example.com/a/b?c=1", true},
+		{"https://legacy.example.com/*", "https://example.com/legacy.example.com/", false},
+		{"*.example.com", "cdn.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"page-?.html", "page-1.html", true},
+		{"page-?.html", "page-10.html", false},
+		{"a.b", "axb", false},
+		{"(x)+[y]", "(x)+[y]", true},
+		{"", "", true},
+		{"", "x", false},
+	}
+	for _, tc := range cases {
+		if got := Compile(tc.pattern).MatchString(tc.input); got != tc.want {
+			t.Errorf("Compile(%q).MatchString(%q) = %v, want %v", tc.pattern, tc.input, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"linkcheck/internal/crawler"
+)
+
+// FailOn is the lowest severity that fails a run. The zero value never fails.
+type FailOn crawler.Severity
+
+// ParseFailOn parses a --fail-on value: "error", "warning", "info" or "none".
+func ParseFailOn(value string) (FailOn, error) {
+	switch s := crawler.Severity(strings.ToLower(strings.TrimSpace(value))); s {
+	case crawler.SeverityError, crawler.SeverityWarning, crawler.SeverityInfo:
+		return FailOn(s), nil
+	case "none":
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid fail-on value %q, want error, warning, info or none", value)
+	}
+}
+
+// Failed reports whether any error or audit finding in r reaches the
+// threshold.
+func (f FailOn) Failed(r *crawler.Report) bool {
+	if f == "" || r == nil {
+		return false
+	}
+	threshold := crawler.Severity(f).Rank()
+	for _, e := range r.Errors {
+		if e.Severity.Rank() >= threshold {
+			return true
+		}
+	}
+	for _, finding := range r.Findings {
+		if finding.Severity.Rank() >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns 1 when r fails the threshold and 0 otherwise.
+func (f FailOn) ExitCode(r *crawler.Report) int {
+	if f.Failed(r) {
+		return 1
+	}
+	return 0
+}
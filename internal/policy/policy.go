@@ -0,0 +1,255 @@
+// Package policy assigns severities to crawl errors and decides whether a run
+// should fail. Rules are matched in order and the first match wins, so more
+// specific rules belong at the top:
+//
+//	default: error
+//	rules:
+//	  - type: http
+//	    status: 403,429
+//	    scope: external
+//	    severity: warning
+//	  - status: 5xx
+//	    scope: external
+//	    severity: warning
+//	  - host: "*.bot-hostile.example"
+//	    severity: info
+//
+// Every condition of a rule must hold for it to match; omitted conditions
+// match anything.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"linkcheck/internal/crawler"
+	"linkcheck/internal/glob"
+)
+
+// Policy classifies errors by severity.
+type Policy struct {
+	Default crawler.Severity `yaml:"default"`
+	Rules   []Rule           `yaml:"rules"`
+}
+
+// Rule assigns Severity to errors matching all of its conditions. Type lists
+// error types separated by commas. Status lists codes or ranges such as "404",
+// "4xx" or "500-503". Scope is "internal" or "external". Host and URL are
+// globs on the target URL where "*" matches any run of characters.
+type Rule struct {
+	Type     string           `yaml:"type,omitempty"`
+	Status   string           `yaml:"status,omitempty"`
+	Scope    string           `yaml:"scope,omitempty"`
+	Host     string           `yaml:"host,omitempty"`
+	URL      string           `yaml:"url,omitempty"`
+	Severity crawler.Severity `yaml:"severity"`
+
+	types    map[string]struct{}
+	statuses []statusRange
+	host     *regexp.Regexp
+	url      *regexp.Regexp
+}
+
+type statusRange struct {
+	low, high int
+}
+
+// Default returns the built-in policy: internal failures are errors, external
+// 401/403/429 responses from bot-hostile sites, external 5xx responses, http
+// links on https pages and unexpected content types are warnings, and markdown
+// export, rate limiter or PDF parsing problems are informational.
+func Default() *Policy {
+	p := &Policy{
+		Default: crawler.SeverityError,
+		Rules: []Rule{
+			{Type: "markdown", Severity: crawler.SeverityInfo},
+			{Type: "rate", Severity: crawler.SeverityInfo},
+			{Type: "pdf", Severity: crawler.SeverityInfo},
+			{Type: "http", Status: "401,403,429", Scope: "external", Severity: crawler.SeverityWarning},
+			{Type: "http", Status: "5xx", Scope: "external", Severity: crawler.SeverityWarning},
+			{Type: "insecure-link", Severity: crawler.SeverityWarning},
+			{Type: "content-type", Severity: crawler.SeverityWarning},
+		},
+	}
+	if err := p.compile(); err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Load reads a policy file. An empty path or a missing file yields Default.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(payload)
+}
+
+// Parse decodes and validates a YAML policy.
+func Parse(payload []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.Unmarshal(payload, p); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if p.Default == "" {
+		p.Default = crawler.SeverityError
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Policy) compile() error {
+	if p.Default.Rank() == 0 {
+		return fmt.Errorf("invalid default severity %q", p.Default)
+	}
+	for i := range p.Rules {
+		if err := p.Rules[i].compile(); err != nil {
+			return fmt.Errorf("policy rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) compile() error {
+	if r.Severity == "" || r.Severity.Rank() == 0 {
+		return fmt.Errorf("invalid severity %q", r.Severity)
+	}
+	switch r.Scope {
+	case "", "internal", "external":
+	default:
+		return fmt.Errorf("invalid scope %q, want internal or external", r.Scope)
+	}
+	if r.Type != "" {
+		r.types = make(map[string]struct{})
+		for _, t := range strings.Split(r.Type, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				r.types[t] = struct{}{}
+			}
+		}
+	}
+	if r.Status != "" {
+		ranges, err := parseStatusRanges(r.Status)
+		if err != nil {
+			return err
+		}
+		r.statuses = ranges
+	}
+	if r.Host != "" {
+		r.host = glob.Compile(strings.ToLower(r.Host))
+	}
+	if r.URL != "" {
+		r.url = glob.Compile(r.URL)
+	}
+	return nil
+}
+
+// Classify returns the severity of e under the policy.
+func (p *Policy) Classify(e crawler.Error) crawler.Severity {
+	if p == nil {
+		return crawler.SeverityError
+	}
+	for _, rule := range p.Rules {
+		if rule.matches(e) {
+			return rule.Severity
+		}
+	}
+	return p.Default
+}
+
+// Apply sets the severity of every error in r.
+func (p *Policy) Apply(r *crawler.Report) {
+	if r == nil {
+		return
+	}
+	for i := range r.Errors {
+		r.Errors[i].Severity = p.Classify(r.Errors[i])
+	}
+}
+
+func (r Rule) matches(e crawler.Error) bool {
+	if r.types != nil {
+		if _, ok := r.types[e.Type]; !ok {
+			return false
+		}
+	}
+	if r.statuses != nil && !matchesStatus(r.statuses, e.Status) {
+		return false
+	}
+	switch r.Scope {
+	case "internal":
+		if !e.Internal() {
+			return false
+		}
+	case "external":
+		if e.Internal() {
+			return false
+		}
+	}
+	if r.host != nil {
+		parsed, err := url.Parse(e.Target)
+		if err != nil || !r.host.MatchString(strings.ToLower(parsed.Hostname())) {
+			return false
+		}
+	}
+	if r.url != nil && !r.url.MatchString(e.Target) {
+		return false
+	}
+	return true
+}
+
+func parseStatusRanges(spec string) ([]statusRange, error) {
+	var ranges []statusRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "":
+			continue
+		case len(part) == 3 && strings.HasSuffix(part, "xx"):
+			class, err := strconv.Atoi(part[:1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid status class %q", part)
+			}
+			ranges = append(ranges, statusRange{class * 100, class*100 + 99})
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			low, errLow := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			high, errHigh := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if errLow != nil || errHigh != nil || low > high {
+				return nil, fmt.Errorf("invalid status range %q", part)
+			}
+			ranges = append(ranges, statusRange{low, high})
+		default:
+			code, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status %q", part)
+			}
+			ranges = append(ranges, statusRange{code, code})
+		}
+	}
+	return ranges, nil
+}
+
+func matchesStatus(ranges []statusRange, status int) bool {
+	for _, r := range ranges {
+		if status >= r.low && status <= r.high {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"testing"
+
+	"linkcheck/internal/crawler"
+)
+
+func externalError(target string, status int) crawler.Error {
+	return crawler.Error{
+		Source: "https://example.test/",
+		Target: target,
+		Type:   "http",
+		Status: status,
+		Link:   &crawler.Link{URL: target, Type: crawler.LinkTypeExternal},
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p := Default()
+	cases := []struct {
+		name string
+		err  crawler.Error
+		want crawler.Severity
+	}{
+		{"internal 404", crawler.Error{Source: "https://example.test/", Target: "https://example.test/x", Type: "http", Status: 404}, crawler.SeverityError},
+		{"external 403", externalError("https://bots.test/", 403), crawler.SeverityWarning},
+		{"external 503", externalError("https://flaky.test/", 503), crawler.SeverityWarning},
+		{"external 404", externalError("https://gone.test/", 404), crawler.SeverityError},
+		{"markdown", crawler.Error{Type: "markdown"}, crawler.SeverityInfo},
+		{"content type", crawler.Error{Type: "content-type"}, crawler.SeverityWarning},
+		{"pdf", crawler.Error{Type: "pdf"}, crawler.SeverityInfo},
+	}
+	for _, tc := range cases {
+		if got := p.Classify(tc.err); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := Parse([]byte(`default: warning
+rules:
+  - host: "*.legacy.test"
+    severity: info
+  - type: http,request
+    status: 400-404, 410
+    scope: internal
+    severity: error
+`))
+	if err != nil {
+		t.Fatalf("parse policy: %v", err)
+	}
+	if got := p.Classify(externalError("https://docs.legacy.test/a", 404)); got != crawler.SeverityInfo {
+		t.Fatalf("expected host rule to apply, got %q", got)
+	}
+	internal := crawler.Error{Target: "https://example.test/x", Type: "http", Status: 410}
+	if got := p.Classify(internal); got != crawler.SeverityError {
+		t.Fatalf("expected status rule to apply, got %q", got)
+	}
+	internal.Status = 500
+	if got := p.Classify(internal); got != crawler.SeverityWarning {
+		t.Fatalf("expected default severity, got %q", got)
+	}
+
+	for _, invalid := range []string{
+		"rules:\n  - severity: fatal\n",
+		"rules:\n  - status: 9zz\n    severity: error\n",
+		"rules:\n  - scope: sideways\n    severity: error\n",
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestFailOn(t *testing.T) {
+	report := &crawler.Report{Errors: []crawler.Error{
+		externalError("https://flaky.test/", 503),
+		{Type: "markdown"},
+	}}
+	Default().Apply(report)
+
+	for value, want := range map[string]bool{"error": false, "warning": true, "info": true, "none": false} {
+		failOn, err := ParseFailOn(value)
+		if err != nil {
+			t.Fatalf("parse %q: %v", value, err)
+		}
+		if got := failOn.Failed(report); got != want {
+			t.Errorf("fail-on %s: got %v, want %v", value, got, want)
+		}
+	}
+
+	findings := &crawler.Report{Findings: []crawler.Finding{{Check: crawler.CheckTLS, Rule: "self-signed", Severity: crawler.SeverityError}}}
+	for value, want := range map[string]bool{"error": true, "warning": true, "none": false} {
+		failOn, _ := ParseFailOn(value)
+		if got := failOn.Failed(findings); got != want {
+			t.Errorf("fail-on %s with an error finding: got %v, want %v", value, got, want)
+		}
+	}
+	findings.Findings[0].Severity = crawler.SeverityInfo
+	if failOn, _ := ParseFailOn("warning"); failOn.Failed(findings) {
+		t.Error("fail-on warning: an info finding should not fail the run")
+	}
+	if _, err := ParseFailOn("sometimes"); err == nil {
+		t.Fatal("expected error for invalid fail-on value")
+	}
+}
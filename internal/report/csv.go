@@ -0,0 +1,83 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"linkcheck/internal/crawler"
+)
+
+var (
+	errorsCSVHeader = []string{"source", "target", "type", "status", "message", "link_text", "link_rel", "severity"}
+	pagesCSVHeader  = []string{"url", "status", "error", "retrieved_ms", "internal_links", "external_links", "markdown_path", "markdown_skipped_reason"}
+)
+
+// WriteErrorsCSV writes one CSV row per error, preceded by a header row.
+func WriteErrorsCSV(w io.Writer, r *crawler.Report) error {
+	doc := New(r)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(errorsCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range doc.Errors {
+		row := []string{e.Source, e.Target, e.Type, formatStatus(e.Status), e.Message, "", "", e.Severity}
+		if e.Link != nil {
+			row[5] = e.Link.Text
+			row[6] = strings.Join(e.Link.Rel, " ")
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePagesCSV writes one CSV row per crawled page, preceded by a header row.
+func WritePagesCSV(w io.Writer, r *crawler.Report) error {
+	doc := New(r)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(pagesCSVHeader); err != nil {
+		return err
+	}
+	for _, page := range doc.Pages {
+		internal, external := countLinks(page.Links)
+		row := []string{
+			page.URL,
+			formatStatus(page.Status),
+			page.Error,
+			strconv.FormatInt(page.RetrievedMS, 10),
+			strconv.Itoa(internal),
+			strconv.Itoa(external),
+			page.MarkdownPath,
+			page.MarkdownSkippedReason,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatStatus(status int) string {
+	if status == 0 {
+		return ""
+	}
+	return strconv.Itoa(status)
+}
+
+func countLinks(links []Link) (int, int) {
+	var internal, external int
+	for _, link := range links {
+		switch link.Type {
+		case string(crawler.LinkTypeInternal):
+			internal++
+		case string(crawler.LinkTypeExternal):
+			external++
+		}
+	}
+	return internal, external
+}
@@ -0,0 +1,288 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReadJSON decodes a document written by WriteJSON. Documents with a newer
+// schema version than this package understands are rejected.
+func ReadJSON(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported report schema version %d", doc.SchemaVersion)
+	}
+	return &doc, nil
+}
+
+// DiffOptions tunes Compare. A page's latency counts as a regression when the
+// new retrieval time exceeds the old one by LatencyFactor and by at least
+// LatencyMinDeltaMS. Zero values select a factor of 1.5 and a delta of 200ms.
+// Latency regressions are always reported but only count towards
+// HasRegressions when FailOnLatency is set.
+type DiffOptions struct {
+	LatencyFactor     float64
+	LatencyMinDeltaMS int64
+	FailOnLatency     bool
+}
+
+// Diff describes how a crawl changed between two reports.
+type Diff struct {
+	NewErrors          []Error          `json:"new_errors"`
+	FixedErrors        []Error          `json:"fixed_errors"`
+	AddedPages         []string         `json:"added_pages"`
+	RemovedPages       []string         `json:"removed_pages"`
+	StatusChanges      []StatusChange   `json:"status_changes"`
+	RedirectChanges    []RedirectChange `json:"redirect_changes"`
+	LatencyRegressions []LatencyChange  `json:"latency_regressions"`
+
+	failOnLatency bool
+}
+
+// StatusChange records a page whose HTTP status differs between reports.
+// Regression is set when a page that used to succeed now fails. Status 0
+// marks a page that got no response, such as one blocked by robots.txt or the
+// rate limit, and never counts as a regression; request failures show up as
+// new errors instead.
+type StatusChange struct {
+	URL        string `json:"url"`
+	Old        int    `json:"old"`
+	New        int    `json:"new"`
+	Regression bool   `json:"regression"`
+}
+
+// RedirectChange records a page whose redirect target differs between reports.
+type RedirectChange struct {
+	URL string `json:"url"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// LatencyChange records a page that got noticeably slower.
+type LatencyChange struct {
+	URL   string `json:"url"`
+	OldMS int64  `json:"old_ms"`
+	NewMS int64  `json:"new_ms"`
+}
+
+// Compare reports the differences between an older and a newer document.
+func Compare(before, after *Document, opts DiffOptions) *Diff {
+	if opts.LatencyFactor <= 0 {
+		opts.LatencyFactor = 1.5
+	}
+	if opts.LatencyMinDeltaMS <= 0 {
+		opts.LatencyMinDeltaMS = 200
+	}
+	if before == nil {
+		before = &Document{}
+	}
+	if after == nil {
+		after = &Document{}
+	}
+
+	d := &Diff{
+		NewErrors:          []Error{},
+		FixedErrors:        []Error{},
+		AddedPages:         []string{},
+		RemovedPages:       []string{},
+		StatusChanges:      []StatusChange{},
+		RedirectChanges:    []RedirectChange{},
+		LatencyRegressions: []LatencyChange{},
+		failOnLatency:      opts.FailOnLatency,
+	}
+
+	oldErrors := indexErrors(before.Errors)
+	newErrors := indexErrors(after.Errors)
+	for _, e := range after.Errors {
+		if _, ok := oldErrors[diffErrorKey(e)]; !ok {
+			d.NewErrors = append(d.NewErrors, e)
+		}
+	}
+	for _, e := range before.Errors {
+		if _, ok := newErrors[diffErrorKey(e)]; !ok {
+			d.FixedErrors = append(d.FixedErrors, e)
+		}
+	}
+
+	oldPages := indexPages(before.Pages)
+	newPages := indexPages(after.Pages)
+	for _, page := range after.Pages {
+		prev, ok := oldPages[page.URL]
+		if !ok {
+			d.AddedPages = append(d.AddedPages, page.URL)
+			continue
+		}
+		if prev.Status != page.Status {
+			d.StatusChanges = append(d.StatusChanges, StatusChange{
+				URL:        page.URL,
+				Old:        prev.Status,
+				New:        page.Status,
+				Regression: prev.Status != 0 && prev.Status < 400 && page.Status >= 400,
+			})
+		}
+		if prev.RedirectURL != page.RedirectURL {
+			d.RedirectChanges = append(d.RedirectChanges, RedirectChange{URL: page.URL, Old: prev.RedirectURL, New: page.RedirectURL})
+		}
+		delta := page.RetrievedMS - prev.RetrievedMS
+		if delta >= opts.LatencyMinDeltaMS && float64(page.RetrievedMS) > float64(prev.RetrievedMS)*opts.LatencyFactor {
+			d.LatencyRegressions = append(d.LatencyRegressions, LatencyChange{URL: page.URL, OldMS: prev.RetrievedMS, NewMS: page.RetrievedMS})
+		}
+	}
+	for _, page := range before.Pages {
+		if _, ok := newPages[page.URL]; !ok {
+			d.RemovedPages = append(d.RemovedPages, page.URL)
+		}
+	}
+
+	sort.SliceStable(d.NewErrors, func(i, j int) bool { return errorLess(d.NewErrors[i], d.NewErrors[j]) })
+	sort.SliceStable(d.FixedErrors, func(i, j int) bool { return errorLess(d.FixedErrors[i], d.FixedErrors[j]) })
+	sort.Strings(d.AddedPages)
+	sort.Strings(d.RemovedPages)
+	return d
+}
+
+// HasRegressions reports whether the newer crawl introduced errors, turned a
+// working page into a failing one or, with DiffOptions.FailOnLatency, got
+// noticeably slower.
+func (d *Diff) HasRegressions() bool {
+	if d == nil {
+		return false
+	}
+	if len(d.NewErrors) > 0 || (d.failOnLatency && len(d.LatencyRegressions) > 0) {
+		return true
+	}
+	for _, change := range d.StatusChanges {
+		if change.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns 1 when the diff contains regressions and 0 otherwise.
+func (d *Diff) ExitCode() int {
+	if d.HasRegressions() {
+		return 1
+	}
+	return 0
+}
+
+// WriteDiffJSON writes the diff as an indented JSON document.
+func WriteDiffJSON(w io.Writer, d *Diff) error {
+	payload := struct {
+		Regressions bool `json:"regressions"`
+		*Diff
+	}{Regressions: d.HasRegressions(), Diff: d}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(payload)
+}
+
+// WriteDiffText writes a human-readable summary of the diff.
+func WriteDiffText(w io.Writer, d *Diff) error {
+	ew := &errWriter{w: w}
+	if len(d.NewErrors) > 0 {
+		ew.printf("New errors (%d):\n", len(d.NewErrors))
+		for _, e := range d.NewErrors {
+			ew.printf("  + %s -> %s [%s] %s\n", e.Source, e.Target, e.Type, e.Message)
+		}
+	}
+	if len(d.FixedErrors) > 0 {
+		ew.printf("Fixed errors (%d):\n", len(d.FixedErrors))
+		for _, e := range d.FixedErrors {
+			ew.printf("  - %s -> %s [%s] %s\n", e.Source, e.Target, e.Type, e.Message)
+		}
+	}
+	if len(d.StatusChanges) > 0 {
+		ew.printf("Status changes (%d):\n", len(d.StatusChanges))
+		for _, c := range d.StatusChanges {
+			marker := " "
+			if c.Regression {
+				marker = "!"
+			}
+			ew.printf("  %s %s: %d -> %d\n", marker, c.URL, c.Old, c.New)
+		}
+	}
+	if len(d.RedirectChanges) > 0 {
+		ew.printf("Redirect changes (%d):\n", len(d.RedirectChanges))
+		for _, c := range d.RedirectChanges {
+			ew.printf("    %s: %s -> %s\n", c.URL, orNone(c.Old), orNone(c.New))
+		}
+	}
+	if len(d.LatencyRegressions) > 0 {
+		ew.printf("Latency regressions (%d):\n", len(d.LatencyRegressions))
+		marker := " "
+		if d.failOnLatency {
+			marker = "!"
+		}
+		for _, c := range d.LatencyRegressions {
+			ew.printf("  %s %s: %dms -> %dms\n", marker, c.URL, c.OldMS, c.NewMS)
+		}
+	}
+	if len(d.AddedPages) > 0 {
+		ew.printf("Pages added (%d):\n", len(d.AddedPages))
+		for _, u := range d.AddedPages {
+			ew.printf("  + %s\n", u)
+		}
+	}
+	if len(d.RemovedPages) > 0 {
+		ew.printf("Pages removed (%d):\n", len(d.RemovedPages))
+		for _, u := range d.RemovedPages {
+			ew.printf("  - %s\n", u)
+		}
+	}
+	if d.HasRegressions() {
+		ew.printf("Result: regressions found\n")
+	} else {
+		ew.printf("Result: no regressions\n")
+	}
+	return ew.err
+}
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+type errorKey struct {
+	source, target, kind string
+}
+
+func diffErrorKey(e Error) errorKey {
+	return errorKey{source: e.Source, target: e.Target, kind: e.Type}
+}
+
+func indexErrors(errs []Error) map[errorKey]struct{} {
+	index := make(map[errorKey]struct{}, len(errs))
+	for _, e := range errs {
+		index[diffErrorKey(e)] = struct{}{}
+	}
+	return index
+}
+
+func indexPages(pages []Page) map[string]Page {
+	index := make(map[string]Page, len(pages))
+	for _, page := range pages {
+		index[page.URL] = page
+	}
+	return index
+}
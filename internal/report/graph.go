@@ -0,0 +1,280 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"linkcheck/internal/crawler"
+)
+
+// GraphOptions tunes the link graph exporters. IncludeExternal adds external
+// link targets as nodes. CollapseDepth, when positive, merges internal pages
+// into one node per host and leading path segments, so /docs/a and /docs/b
+// become /docs with CollapseDepth 1; links within a merged node are dropped.
+type GraphOptions struct {
+	IncludeExternal bool
+	CollapseDepth   int
+}
+
+// Graph is the crawl as a directed graph with pages as nodes and links as
+// edges. Nodes are sorted by ID and edges by source and target.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a page, a collapsed group of pages or an external target.
+// Status is the worst status within the node and Depth the smallest crawl
+// depth, or -1 when the node was never crawled. Pages counts the crawled
+// pages merged into the node.
+type GraphNode struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status int    `json:"status,omitempty"`
+	Depth  int    `json:"depth"`
+	Pages  int    `json:"pages"`
+}
+
+// GraphEdge aggregates the links from one node to another. Text is the first
+// non-empty anchor text and Count the number of links.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Text   string `json:"text,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// NewGraph builds the link graph of r.
+func NewGraph(r *crawler.Report, opts GraphOptions) *Graph {
+	doc := New(r)
+	nodes := map[string]*GraphNode{}
+	node := func(id, kind string) *GraphNode {
+		n, ok := nodes[id]
+		if !ok {
+			n = &GraphNode{ID: id, Type: kind, Depth: -1}
+			nodes[id] = n
+		}
+		return n
+	}
+	nodeID := func(raw string) string {
+		if opts.CollapseDepth < 1 {
+			return raw
+		}
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		return parsed.Host + crawler.PathPrefix(parsed.Path, opts.CollapseDepth)
+	}
+
+	for _, page := range doc.Pages {
+		n := node(nodeID(page.URL), string(crawler.LinkTypeInternal))
+		n.Pages++
+		if page.Status > n.Status {
+			n.Status = page.Status
+		}
+		if n.Depth < 0 || page.Depth < n.Depth {
+			n.Depth = page.Depth
+		}
+	}
+
+	type edgeKey struct{ source, target string }
+	edges := map[edgeKey]*GraphEdge{}
+	for _, page := range doc.Pages {
+		source := nodeID(page.URL)
+		for _, link := range page.Links {
+			var target string
+			switch crawler.LinkType(link.Type) {
+			case crawler.LinkTypeInternal:
+				target = nodeID(link.URL)
+				node(target, link.Type)
+			case crawler.LinkTypeExternal:
+				if !opts.IncludeExternal {
+					continue
+				}
+				target = link.URL
+				node(target, link.Type)
+			default:
+				continue
+			}
+			if opts.CollapseDepth > 0 && source == target {
+				continue
+			}
+			key := edgeKey{source, target}
+			e, ok := edges[key]
+			if !ok {
+				e = &GraphEdge{Source: source, Target: target}
+				edges[key] = e
+			}
+			e.Count++
+			if e.Text == "" {
+				e.Text = link.Text
+			}
+		}
+	}
+
+	// External targets are never crawled; their status comes from the
+	// errors recorded while validating them.
+	for _, e := range doc.Errors {
+		if n, ok := nodes[e.Target]; ok && n.Type == string(crawler.LinkTypeExternal) && e.Status > n.Status {
+			n.Status = e.Status
+		}
+	}
+
+	g := &Graph{Nodes: make([]GraphNode, 0, len(nodes)), Edges: make([]GraphEdge, 0, len(edges))}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, *n)
+	}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, *e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		return g.Edges[i].Target < g.Edges[j].Target
+	})
+	return g
+}
+
+// WriteGraphJSON writes the link graph as a JSON node and edge list.
+func WriteGraphJSON(w io.Writer, r *crawler.Report, opts GraphOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewGraph(r, opts))
+}
+
+// WriteDOT writes the link graph in Graphviz DOT format. Failing nodes are
+// drawn red and external targets as boxes.
+func WriteDOT(w io.Writer, r *crawler.Report, opts GraphOptions) error {
+	g := NewGraph(r, opts)
+	ew := &errWriter{w: w}
+	ew.printf("digraph linkcheck {\n")
+	ew.printf("  node [shape=ellipse];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{
+			"label=" + dotQuote(n.ID),
+			"type=" + dotQuote(n.Type),
+			"depth=" + strconv.Itoa(n.Depth),
+		}
+		if n.Status != 0 {
+			attrs = append(attrs, "status="+strconv.Itoa(n.Status))
+		}
+		if opts.CollapseDepth > 0 {
+			attrs = append(attrs, "pages="+strconv.Itoa(n.Pages))
+		}
+		if n.Type == string(crawler.LinkTypeExternal) {
+			attrs = append(attrs, "shape=box")
+		}
+		if n.Status >= 400 {
+			attrs = append(attrs, "color=red")
+		}
+		ew.printf("  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		attrs := []string{"count=" + strconv.Itoa(e.Count)}
+		if e.Text != "" {
+			attrs = append(attrs, "label="+dotQuote(e.Text))
+		}
+		ew.printf("  %s -> %s [%s];\n", dotQuote(e.Source), dotQuote(e.Target), strings.Join(attrs, ", "))
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+func dotQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	Name     string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the link graph as GraphML. Node ids are opaque; the
+// URL or path prefix is stored in the "url" attribute.
+func WriteGraphML(w io.Writer, r *crawler.Report, opts GraphOptions) error {
+	g := NewGraph(r, opts)
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "url", For: "node", Name: "url", AttrType: "string"},
+			{ID: "type", For: "node", Name: "type", AttrType: "string"},
+			{ID: "status", For: "node", Name: "status", AttrType: "int"},
+			{ID: "depth", For: "node", Name: "depth", AttrType: "int"},
+			{ID: "pages", For: "node", Name: "pages", AttrType: "int"},
+			{ID: "text", For: "edge", Name: "text", AttrType: "string"},
+			{ID: "count", For: "edge", Name: "count", AttrType: "int"},
+		},
+		Graph: graphMLGraph{ID: "linkcheck", EdgeDefault: "directed"},
+	}
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: id, Data: []graphMLData{
+			{Key: "url", Value: n.ID},
+			{Key: "type", Value: n.Type},
+			{Key: "status", Value: strconv.Itoa(n.Status)},
+			{Key: "depth", Value: strconv.Itoa(n.Depth)},
+			{Key: "pages", Value: strconv.Itoa(n.Pages)},
+		}})
+	}
+	for _, e := range g.Edges {
+		data := []graphMLData{{Key: "count", Value: strconv.Itoa(e.Count)}}
+		if e.Text != "" {
+			data = append(data, graphMLData{Key: "text", Value: e.Text})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: ids[e.Source], Target: ids[e.Target], Data: data})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,312 @@
+package report
+
+import (
+	"html/template"
+	"io"
+
+	"linkcheck/internal/crawler"
+)
+
+type htmlView struct {
+	Doc      *Document
+	BySource []htmlSourceGroup
+	Skips    []htmlCounter
+	Graph    []htmlGraphNode
+}
+
+type htmlSourceGroup struct {
+	Source string
+	Errors []Error
+}
+
+type htmlCounter struct {
+	Label string
+	Value int
+}
+
+// htmlGraphNode is a section of the site with the links leaving and entering
+// it.
+type htmlGraphNode struct {
+	GraphNode
+	Inbound  int
+	Outbound []GraphEdge
+}
+
+// htmlGraphDepth is the number of directories that form a section in the link
+// graph view, keeping the view readable for large crawls.
+const htmlGraphDepth = 1
+
+// WriteHTML writes the report as a single static HTML page with inline styles
+// and scripts. Broken links are listed by target and by source page, every
+// page has a collapsible detail view, and all tables can be sorted and
+// filtered in the browser without network access. The link graph view shows
+// how the sections of the site, pages grouped by their top-level directory,
+// link to each other.
+func WriteHTML(w io.Writer, r *crawler.Report) error {
+	doc := New(r)
+	view := htmlView{
+		Doc:      doc,
+		BySource: groupErrorsBySource(doc.Errors),
+		Skips: []htmlCounter{
+			{"Skipped by cache", doc.Stats.SkippedByCache},
+			{"Skipped by robots.txt", doc.Stats.SkippedByRobots},
+			{"Skipped by extension", doc.Stats.SkippedByExtension},
+			{"Skipped by page limit", doc.Stats.SkippedByLimit},
+			{"Skipped by depth", doc.Stats.SkippedByDepth},
+			{"Skipped by nofollow page", doc.Stats.SkippedByNofollow},
+			{"Skipped by rel=nofollow", doc.Stats.SkippedByRelNofollow},
+		},
+		Graph: graphNodes(NewGraph(r, GraphOptions{CollapseDepth: htmlGraphDepth})),
+	}
+	return htmlReportTemplate.Execute(w, view)
+}
+
+// graphNodes attaches the edges of g to their nodes, keeping the node order.
+func graphNodes(g *Graph) []htmlGraphNode {
+	nodes := make([]htmlGraphNode, len(g.Nodes))
+	index := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		nodes[i] = htmlGraphNode{GraphNode: n}
+		index[n.ID] = i
+	}
+	for _, e := range g.Edges {
+		nodes[index[e.Source]].Outbound = append(nodes[index[e.Source]].Outbound, e)
+		nodes[index[e.Target]].Inbound += e.Count
+	}
+	return nodes
+}
+
+func groupErrorsBySource(errs []Error) []htmlSourceGroup {
+	var groups []htmlSourceGroup
+	for _, e := range errs {
+		if n := len(groups); n > 0 && groups[n-1].Source == e.Source {
+			groups[n-1].Errors = append(groups[n-1].Errors, e)
+			continue
+		}
+		groups = append(groups, htmlSourceGroup{Source: e.Source, Errors: []Error{e}})
+	}
+	return groups
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"statusClass": func(status int) string {
+		switch {
+		case status == 0:
+			return "unknown"
+		case status >= 400:
+			return "bad"
+		case status >= 300:
+			return "redirect"
+		default:
+			return "ok"
+		}
+	},
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>linkcheck report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+h1, h2 { margin-top: 2rem; }
+table { border-collapse: collapse; width: 100%; margin-top: .5rem; font-size: .9rem; }
+th, td { border: 1px solid #ddd; padding: .3rem .5rem; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+th[data-dir="asc"]::after { content: " \25B2"; }
+th[data-dir="desc"]::after { content: " \25BC"; }
+td.num { text-align: right; }
+input.filter { margin-top: .5rem; padding: .3rem; width: 24rem; max-width: 100%; }
+.bad { color: #b00020; font-weight: bold; }
+.redirect { color: #8a6d00; }
+.ok { color: #1b7a1b; }
+.unknown { color: #777; }
+.sev-error { color: #b00020; }
+.sev-warning { color: #8a6d00; }
+.sev-info { color: #555; }
+.notice { background: #fff4e5; border: 1px solid #f0c36d; padding: .5rem 1rem; }
+details { margin: .2rem 0; }
+summary { cursor: pointer; }
+ul { margin: .3rem 0 .3rem 1.5rem; padding: 0; }
+</style>
+</head>
+<body>
+<h1>linkcheck report</h1>
+<p>Started {{.Doc.StartedAt.Format "2006-01-02 15:04:05 MST"}}, finished {{.Doc.FinishedAt.Format "2006-01-02 15:04:05 MST"}} ({{.Doc.Stats.DurationMS}} ms).</p>
+{{if .Doc.Partial}}<p class="notice">This crawl was {{if .Doc.Cancelled}}cancelled{{else}}stopped by its time limit{{end}}; {{len .Doc.Unvisited}} queued URLs were never visited.</p>{{end}}
+
+<h2>Summary</h2>
+<table>
+<tr><td>Pages visited</td><td class="num">{{.Doc.Stats.PagesVisited}}</td></tr>
+<tr><td>Unique internal pages</td><td class="num">{{.Doc.Stats.UniqueInternalPages}}</td></tr>
+<tr><td>Unique external links</td><td class="num">{{.Doc.Stats.UniqueExternalLinks}}</td></tr>
+<tr><td>External links checked</td><td class="num">{{.Doc.Stats.ExternalLinksChecked}}</td></tr>
+<tr><td>Errors</td><td class="num">{{len .Doc.Errors}}</td></tr>
+{{range .Skips}}<tr><td>{{.Label}}</td><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Broken links by target</h2>
+<input class="filter" type="search" placeholder="Filter targets" data-table="by-target">
+<table id="by-target" class="sortable">
+<thead><tr><th>Target</th><th>Type</th><th>Status</th><th>Message</th><th>Referrers</th></tr></thead>
+<tbody>
+{{range .Doc.BrokenTargets}}<tr><td>{{.URL}}</td><td>{{.Type}}</td><td class="num {{statusClass .Status}}">{{if .Status}}{{.Status}}{{end}}</td><td>{{.Message}}</td><td data-sort="{{len .Referrers}}"><details><summary>{{len .Referrers}} page(s)</summary><ul>{{range .Referrers}}<li>{{.Source}}{{if .Line}} (line {{.Line}}){{end}}{{if .Text}} &ldquo;{{.Text}}&rdquo;{{end}}</li>{{end}}</ul></details></td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Broken links by source page</h2>
+<input class="filter" type="search" placeholder="Filter source pages" data-table="by-source">
+<table id="by-source" class="sortable">
+<thead><tr><th>Source page</th><th>Errors</th><th>Details</th></tr></thead>
+<tbody>
+{{range .BySource}}<tr><td>{{.Source}}</td><td class="num">{{len .Errors}}</td><td><ul>{{range .Errors}}<li>{{if .Severity}}<span class="sev-{{.Severity}}">[{{.Severity}}]</span> {{end}}{{.Target}}{{with .Link}}{{if .Text}} &ldquo;{{.Text}}&rdquo;{{end}}{{if .Page}} (PDF page {{.Page}}){{end}}{{end}} &ndash; {{.Type}}{{if .Status}} {{.Status}}{{end}}: {{.Message}}</li>{{end}}</ul></td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Pages</h2>
+<input class="filter" type="search" placeholder="Filter pages" data-table="pages">
+<table id="pages" class="sortable">
+<thead><tr><th>URL</th><th>Status</th><th>Retrieved (ms)</th><th>Links</th><th>Markdown</th><th>Error</th></tr></thead>
+<tbody>
+{{range .Doc.Pages}}<tr><td>{{.URL}}</td><td class="num {{statusClass .Status}}">{{if .Status}}{{.Status}}{{end}}</td><td class="num">{{.RetrievedMS}}</td><td data-sort="{{len .Links}}">{{if .Links}}<details><summary>{{len .Links}} link(s)</summary><ul>{{range .Links}}<li>[{{.Type}}] {{.URL}}{{if .Text}} &ldquo;{{.Text}}&rdquo;{{end}}{{if .Rel}} <small>rel={{range $i, $r := .Rel}}{{if $i}} {{end}}{{$r}}{{end}}</small>{{end}}</li>{{end}}</ul></details>{{else}}0{{end}}</td><td>{{if .MarkdownPath}}{{.MarkdownPath}}{{else if .MarkdownSkippedReason}}skipped: {{.MarkdownSkippedReason}}{{end}}</td><td>{{.Error}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Graph}}
+<h2>Link graph</h2>
+<p>Pages are grouped into sections by their top-level directory. Links within a section are not shown.</p>
+<input class="filter" type="search" placeholder="Filter sections" data-table="link-graph">
+<table id="link-graph" class="sortable">
+<thead><tr><th>Section</th><th>Pages</th><th>Depth</th><th>Status</th><th>Inbound links</th><th>Links to</th></tr></thead>
+<tbody>
+{{range .Graph}}<tr><td>{{.ID}}</td><td class="num">{{.Pages}}</td><td class="num" data-sort="{{.Depth}}">{{if lt .Depth 0}}not crawled{{else}}{{.Depth}}{{end}}</td><td class="num {{statusClass .Status}}">{{if .Status}}{{.Status}}{{end}}</td><td class="num">{{.Inbound}}</td><td data-sort="{{len .Outbound}}">{{if .Outbound}}<details><summary>{{len .Outbound}} section(s)</summary><ul>{{range .Outbound}}<li>{{.Target}} ({{.Count}}){{if .Text}} &ldquo;{{.Text}}&rdquo;{{end}}</li>{{end}}</ul></details>{{else}}0{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{with .Doc.Structure}}
+<h2>Site structure</h2>
+{{if .DeadEnds}}<p>Dead ends (no outbound internal links):</p>
+<ul>{{range .DeadEnds}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .RedirectOnly}}<p>Reachable only via redirects:</p>
+<ul>{{range .RedirectOnly}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Orphans}}<p>Orphans (listed in the sitemap but never linked):</p>
+<ul>{{range .Orphans}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<input class="filter" type="search" placeholder="Filter pages" data-table="structure">
+<table id="structure" class="sortable">
+<thead><tr><th>URL</th><th>Depth</th><th>Inbound</th><th>Outbound</th></tr></thead>
+<tbody>
+{{range .Pages}}<tr><td>{{.URL}}</td><td class="num" data-sort="{{.Depth}}">{{if lt .Depth 0}}unreachable{{else}}{{.Depth}}{{end}}</td><td class="num">{{.Inbound}}</td><td class="num">{{.Outbound}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Findings}}
+<h2>Audit findings</h2>
+<input class="filter" type="search" placeholder="Filter findings" data-table="findings">
+<table id="findings" class="sortable">
+<thead><tr><th>Page</th><th>Check</th><th>Rule</th><th>Severity</th><th>Message</th><th>Snippet</th></tr></thead>
+<tbody>
+{{range .Doc.Findings}}<tr><td>{{.URL}}{{if .Line}} (line {{.Line}}){{end}}</td><td>{{.Check}}</td><td>{{.Rule}}</td><td class="sev-{{.Severity}}">{{.Severity}}</td><td>{{.Message}}</td><td>{{if .Snippet}}<code>{{.Snippet}}</code>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.TLS}}
+<h2>TLS certificates</h2>
+<table id="tls" class="sortable">
+<thead><tr><th>Host</th><th>Protocol</th><th>Subject</th><th>Issuer</th><th>Expires</th><th>Names</th><th>Problems</th></tr></thead>
+<tbody>
+{{range .Doc.TLS}}<tr><td>{{.Host}}</td><td>{{.Version}}</td><td>{{.Subject}}</td><td>{{.Issuer}}</td><td>{{with .NotAfter}}{{.Format "2006-01-02"}}{{end}}</td><td>{{range $i, $n := .DNSNames}}{{if $i}}, {{end}}{{$n}}{{end}}</td><td>{{if .SelfSigned}}self-signed {{end}}{{if .HostnameMismatch}}hostname mismatch {{end}}{{if .VersionTooOld}}old protocol {{end}}{{.Error}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Headers}}
+<h2>Security headers</h2>
+<table id="security-headers" class="sortable">
+<thead><tr><th>Host</th><th>Prefix</th><th>Pages</th><th>Issues (pages affected)</th></tr></thead>
+<tbody>
+{{range .Doc.Headers}}<tr><td>{{.Host}}</td><td>{{if .Prefix}}{{.Prefix}}{{else}}(all){{end}}</td><td class="num">{{.Pages}}</td><td>{{if .Issues}}<ul>{{range $rule, $pages := .Issues}}<li>{{$rule}}: {{$pages}}</li>{{end}}</ul>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Performance}}
+<h2>Performance</h2>
+<table id="performance" class="sortable">
+<thead><tr><th>Host</th><th>Prefix</th><th>Pages</th><th>TTFB p50/p90/p99 (ms)</th><th>Retrieved p50/p90/p99 (ms)</th><th>Size p50/p90/p99 (bytes)</th></tr></thead>
+<tbody>
+{{range .Doc.Performance}}<tr><td>{{.Host}}</td><td>{{if .Prefix}}{{.Prefix}}{{else}}(all){{end}}</td><td class="num">{{.Pages}}</td><td class="num" data-sort="{{.TTFBMS.P90}}">{{with .TTFBMS}}{{.P50}} / {{.P90}} / {{.P99}}{{end}}</td><td class="num" data-sort="{{.RetrievedMS.P90}}">{{with .RetrievedMS}}{{.P50}} / {{.P90}} / {{.P99}}{{end}}</td><td class="num" data-sort="{{.SizeBytes.P90}}">{{with .SizeBytes}}{{.P50}} / {{.P90}} / {{.P99}}{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Duplicates}}
+<h2>Duplicate content</h2>
+<p>Pages with identical or nearly identical text often lack a canonical tag or differ only in query parameters.</p>
+<table id="duplicates" class="sortable">
+<thead><tr><th>Kind</th><th>Pages</th></tr></thead>
+<tbody>
+{{range .Doc.Duplicates}}<tr><td>{{.Kind}}</td><td data-sort="{{len .Pages}}"><ul>{{range .Pages}}<li>{{.}}</li>{{end}}</ul></td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Ignored}}
+<h2>Ignored errors</h2>
+<table id="ignored" class="sortable">
+<thead><tr><th>Source page</th><th>Target</th><th>Type</th><th>Message</th><th>Rule</th><th>Reason</th></tr></thead>
+<tbody>
+{{range .Doc.Ignored}}<tr><td>{{.Source}}</td><td>{{.Target}}</td><td>{{.Type}}</td><td>{{.Message}}</td><td>{{.Rule}}</td><td>{{.Reason}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.Baselined}}
+<h2>Known errors suppressed by the baseline</h2>
+<table id="baselined" class="sortable">
+<thead><tr><th>Source page</th><th>Target</th><th>Type</th><th>Message</th></tr></thead>
+<tbody>
+{{range .Doc.Baselined}}<tr><td>{{.Source}}</td><td>{{.Target}}</td><td>{{.Type}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Doc.BaselineFixed}}
+<h2>Baseline entries that have been fixed</h2>
+<p>These errors are recorded in the baseline but no longer occur. Refresh the baseline to drop them.</p>
+<ul>{{range .Doc.BaselineFixed}}<li>{{.Source}} &rarr; {{.Target}} ({{.Type}})</li>{{end}}</ul>
+{{end}}
+{{if .Doc.Unvisited}}
+<h2>Unvisited URLs</h2>
+<ul>{{range .Doc.Unvisited}}<li>{{.}}</li>{{end}}</ul>
+{{end}}
+<script>
+(function () {
+  function cellValue(row, index) {
+    var cell = row.cells[index];
+    if (!cell) { return ""; }
+    return cell.getAttribute("data-sort") || cell.textContent.trim();
+  }
+  document.querySelectorAll("table.sortable").forEach(function (table) {
+    table.querySelectorAll("th").forEach(function (th, index) {
+      th.addEventListener("click", function () {
+        var dir = th.getAttribute("data-dir") === "asc" ? "desc" : "asc";
+        table.querySelectorAll("th").forEach(function (other) { other.removeAttribute("data-dir"); });
+        th.setAttribute("data-dir", dir);
+        var body = table.tBodies[0];
+        var rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (a, b) {
+          var x = cellValue(a, index), y = cellValue(b, index);
+          var nx = parseFloat(x), ny = parseFloat(y);
+          var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+          return dir === "asc" ? cmp : -cmp;
+        });
+        rows.forEach(function (row) { body.appendChild(row); });
+      });
+    });
+  });
+  document.querySelectorAll("input.filter").forEach(function (input) {
+    var table = document.getElementById(input.getAttribute("data-table"));
+    input.addEventListener("input", function () {
+      var needle = input.value.toLowerCase();
+      Array.prototype.forEach.call(table.tBodies[0].rows, function (row) {
+        row.style.display = row.textContent.toLowerCase().indexOf(needle) === -1 ? "none" : "";
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"linkcheck/internal/crawler"
+)
+
+// WriteJSON writes the report as a single indented JSON document.
+func WriteJSON(w io.Writer, r *crawler.Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(New(r))
+}
+
+// NDJSON record kinds, stored in the "record" field of every line.
+const (
+	RecordSummary = "summary"
+	RecordPage    = "page"
+	RecordError   = "error"
+	RecordFinding = "finding"
+)
+
+type summaryRecord struct {
+	Record        string      `json:"record"`
+	SchemaVersion int         `json:"schema_version"`
+	StartedAt     time.Time   `json:"started_at"`
+	FinishedAt    time.Time   `json:"finished_at"`
+	Partial       bool        `json:"partial"`
+	Cancelled     bool        `json:"cancelled"`
+	Stats         Stats       `json:"stats"`
+	Unvisited     []string    `json:"unvisited,omitempty"`
+	NoIndex       []string    `json:"noindex,omitempty"`
+	Skipped       []Skip      `json:"skipped,omitempty"`
+	Structure     *Structure  `json:"structure,omitempty"`
+	Duplicates    []Duplicate `json:"duplicates,omitempty"`
+}
+
+type pageRecord struct {
+	Record string `json:"record"`
+	Page
+}
+
+type findingRecord struct {
+	Record string `json:"record"`
+	Finding
+}
+
+type errorRecord struct {
+	Record string `json:"record"`
+	Error
+}
+
+// WriteNDJSON streams the report as newline-delimited JSON. The first line is
+// a summary record, followed by one page record per page, one error record
+// per error and one finding record per audit finding, in the same order as
+// WriteJSON.
+func WriteNDJSON(w io.Writer, r *crawler.Report) error {
+	doc := New(r)
+	enc := json.NewEncoder(w)
+	summary := summaryRecord{
+		Record:        RecordSummary,
+		SchemaVersion: doc.SchemaVersion,
+		StartedAt:     doc.StartedAt,
+		FinishedAt:    doc.FinishedAt,
+		Partial:       doc.Partial,
+		Cancelled:     doc.Cancelled,
+		Stats:         doc.Stats,
+		Unvisited:     doc.Unvisited,
+		NoIndex:       doc.NoIndex,
+		Skipped:       doc.Skipped,
+		Structure:     doc.Structure,
+		Duplicates:    doc.Duplicates,
+	}
+	if err := enc.Encode(summary); err != nil {
+		return err
+	}
+	for _, page := range doc.Pages {
+		if err := enc.Encode(pageRecord{Record: RecordPage, Page: page}); err != nil {
+			return err
+		}
+	}
+	for _, e := range doc.Errors {
+		if err := enc.Encode(errorRecord{Record: RecordError, Error: e}); err != nil {
+			return err
+		}
+	}
+	for _, f := range doc.Findings {
+		if err := enc.Encode(findingRecord{Record: RecordFinding, Finding: f}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"linkcheck/internal/crawler"
+)
+
+// JUnitGrouping selects how testcases are grouped into testsuites.
+type JUnitGrouping int
+
+const (
+	// GroupByHost creates one testsuite per host.
+	GroupByHost JUnitGrouping = iota
+	// GroupByPathPrefix creates one testsuite per host and leading path
+	// segments, as configured by JUnitOptions.PrefixDepth.
+	GroupByPathPrefix
+)
+
+// JUnitOptions configures WriteJUnit.
+type JUnitOptions struct {
+	GroupBy JUnitGrouping
+	// PrefixDepth is the number of path segments used by GroupByPathPrefix.
+	// Values below one are treated as one.
+	PrefixDepth int
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+
+	seconds float64
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Skipped   *junitSkipped  `xml:"skipped,omitempty"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Every crawled page becomes a
+// testcase whose failures are the errors raised while visiting it, and every
+// skipped URL becomes a skipped testcase. Page retrieval time is reported as
+// the testcase time.
+func WriteJUnit(w io.Writer, r *crawler.Report, opts JUnitOptions) error {
+	doc := New(r)
+
+	errorsBySource := make(map[string][]Error)
+	for _, e := range doc.Errors {
+		errorsBySource[e.Source] = append(errorsBySource[e.Source], e)
+	}
+	skipReasons := make(map[string]string, len(doc.Skipped))
+	for _, skip := range doc.Skipped {
+		skipReasons[skip.URL] = skip.Reason
+	}
+
+	suites := make(map[string]*junitTestSuite)
+	suiteFor := func(rawURL string) *junitTestSuite {
+		name := junitSuiteName(rawURL, opts)
+		suite, ok := suites[name]
+		if !ok {
+			suite = &junitTestSuite{Name: name}
+			suites[name] = suite
+		}
+		return suite
+	}
+
+	seen := make(map[string]struct{}, len(doc.Pages))
+	for _, page := range doc.Pages {
+		seen[page.URL] = struct{}{}
+		suite := suiteFor(page.URL)
+		seconds := float64(page.RetrievedMS) / 1000
+		tc := junitTestCase{Name: page.URL, ClassName: suite.Name, Time: formatSeconds(seconds)}
+		if reason, ok := skipReasons[page.URL]; ok {
+			tc.Skipped = &junitSkipped{Message: skipMessage(reason)}
+		}
+		for _, e := range errorsBySource[page.URL] {
+			tc.Failures = append(tc.Failures, newJUnitFailure(e))
+		}
+		suite.add(tc, seconds)
+	}
+	for _, skip := range doc.Skipped {
+		if _, ok := seen[skip.URL]; ok {
+			continue
+		}
+		seen[skip.URL] = struct{}{}
+		suite := suiteFor(skip.URL)
+		suite.add(junitTestCase{
+			Name:      skip.URL,
+			ClassName: suite.Name,
+			Time:      formatSeconds(0),
+			Skipped:   &junitSkipped{Message: skipMessage(skip.Reason)},
+		}, 0)
+	}
+	// Errors whose source was never recorded as a page still need a testcase.
+	for _, e := range doc.Errors {
+		if _, ok := seen[e.Source]; ok {
+			continue
+		}
+		seen[e.Source] = struct{}{}
+		suite := suiteFor(e.Source)
+		tc := junitTestCase{Name: e.Source, ClassName: suite.Name, Time: formatSeconds(0)}
+		for _, err := range errorsBySource[e.Source] {
+			tc.Failures = append(tc.Failures, newJUnitFailure(err))
+		}
+		suite.add(tc, 0)
+	}
+
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := junitTestSuites{Name: "linkcheck", Time: formatSeconds(float64(doc.Stats.DurationMS) / 1000)}
+	for _, name := range names {
+		suite := suites[name]
+		suite.Time = formatSeconds(suite.seconds)
+		root.Tests += suite.Tests
+		root.Failures += suite.Failures
+		root.Skipped += suite.Skipped
+		root.Suites = append(root.Suites, *suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (s *junitTestSuite) add(tc junitTestCase, seconds float64) {
+	s.Tests++
+	if len(tc.Failures) > 0 {
+		s.Failures++
+	} else if tc.Skipped != nil {
+		s.Skipped++
+	}
+	s.seconds += seconds
+	s.Cases = append(s.Cases, tc)
+}
+
+func newJUnitFailure(e Error) junitFailure {
+	var body strings.Builder
+	fmt.Fprintf(&body, "source: %s\n", e.Source)
+	fmt.Fprintf(&body, "target: %s\n", e.Target)
+	fmt.Fprintf(&body, "type: %s\n", e.Type)
+	if e.Status != 0 {
+		fmt.Fprintf(&body, "status: %d\n", e.Status)
+	}
+	if e.Link != nil && e.Link.Text != "" {
+		fmt.Fprintf(&body, "link text: %s\n", e.Link.Text)
+	}
+	if e.Severity != "" {
+		fmt.Fprintf(&body, "severity: %s\n", e.Severity)
+	}
+	return junitFailure{
+		Message: fmt.Sprintf("%s: %s", e.Target, e.Message),
+		Type:    e.Type,
+		Body:    body.String(),
+	}
+}
+
+func skipMessage(reason string) string {
+	switch crawler.SkipReason(reason) {
+	case crawler.SkipReasonRobots:
+		return "blocked by robots.txt"
+	case crawler.SkipReasonExtension:
+		return "extension not allowed"
+	case crawler.SkipReasonDepth:
+		return "beyond maximum depth"
+	case crawler.SkipReasonLimit:
+		return "page limit reached"
+	case crawler.SkipReasonCache:
+		return "visited recently according to cache"
+	case crawler.SkipReasonNofollow:
+		return "linked from a nofollow page"
+	case crawler.SkipReasonRelNofollow:
+		return "link marked rel=nofollow"
+	default:
+		return reason
+	}
+}
+
+func junitSuiteName(rawURL string, opts JUnitOptions) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+	if opts.GroupBy != GroupByPathPrefix {
+		return parsed.Host
+	}
+	return parsed.Host + crawler.PathPrefix(parsed.Path, opts.PrefixDepth)
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
@@ -0,0 +1,552 @@
+// Package report serialises crawler reports into stable, machine-readable
+// formats. All writers share the same Document model so that field names and
+// ordering stay identical across JSON, CSV and NDJSON output.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"linkcheck/internal/crawler"
+)
+
+// SchemaVersion identifies the layout of Document. It is incremented whenever
+// a field is renamed or removed; additive changes keep the version.
+const SchemaVersion = 1
+
+// Document is the serialisable form of a crawler.Report. Pages are sorted by
+// URL and errors by source, target, type, status and message so that two runs
+// over the same site produce byte-identical output.
+type Document struct {
+	SchemaVersion int            `json:"schema_version"`
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+	Partial       bool           `json:"partial"`
+	Cancelled     bool           `json:"cancelled"`
+	Stats         Stats          `json:"stats"`
+	Pages         []Page         `json:"pages"`
+	Errors        []Error        `json:"errors"`
+	Unvisited     []string       `json:"unvisited,omitempty"`
+	NoIndex       []string       `json:"noindex,omitempty"`
+	Skipped       []Skip         `json:"skipped,omitempty"`
+	BrokenTargets []BrokenTarget `json:"broken_targets"`
+	Structure     *Structure     `json:"structure,omitempty"`
+	Duplicates    []Duplicate    `json:"duplicates,omitempty"`
+	Findings      []Finding      `json:"findings,omitempty"`
+	TLS           []TLSHost      `json:"tls,omitempty"`
+	Headers       []HeaderGroup  `json:"security_headers,omitempty"`
+	Performance   []Performance  `json:"performance,omitempty"`
+	Ignored       []IgnoredError `json:"ignored,omitempty"`
+	Baselined     []Error        `json:"baselined,omitempty"`
+	BaselineFixed []Error        `json:"baseline_fixed,omitempty"`
+
+	BaselineApplied bool `json:"baseline_applied,omitempty"`
+}
+
+// Stats mirrors crawler.Stats with durations expressed in milliseconds.
+type Stats struct {
+	PagesVisited         int   `json:"pages_visited"`
+	UniqueInternalPages  int   `json:"unique_internal_pages"`
+	UniqueExternalLinks  int   `json:"unique_external_links"`
+	TotalInternalLinks   int   `json:"total_internal_links"`
+	TotalExternalLinks   int   `json:"total_external_links"`
+	ExternalLinksChecked int   `json:"external_links_checked"`
+	DurationMS           int64 `json:"duration_ms"`
+	SkippedByCache       int   `json:"skipped_by_cache"`
+	SkippedByRobots      int   `json:"skipped_by_robots"`
+	SkippedByExtension   int   `json:"skipped_by_extension"`
+	SkippedByLimit       int   `json:"skipped_by_limit"`
+	SkippedByDepth       int   `json:"skipped_by_depth"`
+	SkippedByNofollow    int   `json:"skipped_by_nofollow"`
+	SkippedByRelNofollow int   `json:"skipped_by_rel_nofollow"`
+}
+
+// Page mirrors crawler.PageReport.
+type Page struct {
+	URL                   string           `json:"url"`
+	RedirectURL           string           `json:"redirect_url,omitempty"`
+	Depth                 int              `json:"depth"`
+	Status                int              `json:"status"`
+	ContentType           string           `json:"content_type,omitempty"`
+	Error                 string           `json:"error,omitempty"`
+	RetrievedMS           int64            `json:"retrieved_ms"`
+	MarkdownPath          string           `json:"markdown_path,omitempty"`
+	MarkdownSkippedReason string           `json:"markdown_skipped_reason,omitempty"`
+	ContentSHA256         string           `json:"content_sha256,omitempty"`
+	SimHash               string           `json:"simhash,omitempty"`
+	SEO                   *SEOMetadata     `json:"seo,omitempty"`
+	Headers               *SecurityHeaders `json:"security_headers,omitempty"`
+	SizeBytes             int64            `json:"size_bytes,omitempty"`
+	Timing                *Timing          `json:"timing,omitempty"`
+	Relations             *LinkRelations   `json:"relations,omitempty"`
+	NoIndex               bool             `json:"noindex,omitempty"`
+	NoFollow              bool             `json:"nofollow,omitempty"`
+	Links                 []Link           `json:"links"`
+}
+
+// SEOMetadata mirrors crawler.SEOMetadata.
+type SEOMetadata struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Canonical   string            `json:"canonical,omitempty"`
+	H1Count     int               `json:"h1_count"`
+	Lang        string            `json:"lang,omitempty"`
+	OpenGraph   map[string]string `json:"open_graph,omitempty"`
+}
+
+// LinkRelations mirrors crawler.LinkRelations.
+type LinkRelations struct {
+	Canonical  string      `json:"canonical,omitempty"`
+	Alternates []Alternate `json:"alternates,omitempty"`
+	Next       string      `json:"next,omitempty"`
+	Prev       string      `json:"prev,omitempty"`
+	AMPHTML    string      `json:"amphtml,omitempty"`
+	Srcset     []string    `json:"srcset,omitempty"`
+}
+
+// Alternate mirrors crawler.Alternate.
+type Alternate struct {
+	Hreflang string `json:"hreflang"`
+	URL      string `json:"url"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// SecurityHeaders mirrors crawler.SecurityHeaders.
+type SecurityHeaders struct {
+	StrictTransportSecurity string        `json:"strict_transport_security,omitempty"`
+	ContentSecurityPolicy   string        `json:"content_security_policy,omitempty"`
+	ContentTypeOptions      string        `json:"x_content_type_options,omitempty"`
+	FrameOptions            string        `json:"x_frame_options,omitempty"`
+	ReferrerPolicy          string        `json:"referrer_policy,omitempty"`
+	Cookies                 []CookieFlags `json:"cookies,omitempty"`
+}
+
+// CookieFlags mirrors crawler.CookieFlags.
+type CookieFlags struct {
+	Name     string `json:"name"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"http_only"`
+	SameSite string `json:"same_site,omitempty"`
+}
+
+// HeaderGroup mirrors crawler.HeaderGroup. Prefix is empty for groups
+// covering a whole host.
+type HeaderGroup struct {
+	Host   string         `json:"host"`
+	Prefix string         `json:"prefix,omitempty"`
+	Pages  int            `json:"pages"`
+	Issues map[string]int `json:"issues,omitempty"`
+}
+
+// Timing mirrors crawler.Timing in milliseconds.
+type Timing struct {
+	DNSMS      int64 `json:"dns_ms"`
+	ConnectMS  int64 `json:"connect_ms"`
+	TLSMS      int64 `json:"tls_ms"`
+	TTFBMS     int64 `json:"ttfb_ms"`
+	DownloadMS int64 `json:"download_ms"`
+}
+
+// Performance mirrors crawler.PerformanceGroup. Prefix is empty for groups
+// covering a whole host.
+type Performance struct {
+	Host        string      `json:"host"`
+	Prefix      string      `json:"prefix,omitempty"`
+	Pages       int         `json:"pages"`
+	TTFBMS      Percentiles `json:"ttfb_ms"`
+	RetrievedMS Percentiles `json:"retrieved_ms"`
+	SizeBytes   Percentiles `json:"size_bytes"`
+}
+
+// Percentiles holds the p50, p90 and p99 of a performance metric.
+type Percentiles struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+}
+
+// Finding mirrors crawler.Finding.
+type Finding struct {
+	Check    string `json:"check"`
+	Rule     string `json:"rule"`
+	URL      string `json:"url"`
+	Message  string `json:"message"`
+	Snippet  string `json:"snippet,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+}
+
+// TLSHost mirrors crawler.TLSHost. NotAfter is nil when no certificate was
+// seen.
+type TLSHost struct {
+	Host             string     `json:"host"`
+	Version          string     `json:"version,omitempty"`
+	Subject          string     `json:"subject,omitempty"`
+	Issuer           string     `json:"issuer,omitempty"`
+	NotAfter         *time.Time `json:"not_after,omitempty"`
+	DNSNames         []string   `json:"dns_names,omitempty"`
+	SelfSigned       bool       `json:"self_signed,omitempty"`
+	HostnameMismatch bool       `json:"hostname_mismatch,omitempty"`
+	VersionTooOld    bool       `json:"version_too_old,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// Link mirrors crawler.Link.
+type Link struct {
+	URL     string   `json:"url"`
+	Type    string   `json:"type"`
+	Element string   `json:"element,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	Line    int      `json:"line,omitempty"`
+	Page    int      `json:"page,omitempty"`
+	Rel     []string `json:"rel,omitempty"`
+	Target  string   `json:"target,omitempty"`
+	Title   string   `json:"title,omitempty"`
+}
+
+// BrokenTarget mirrors crawler.BrokenTarget.
+type BrokenTarget struct {
+	URL       string     `json:"url"`
+	Type      string     `json:"type"`
+	Status    int        `json:"status,omitempty"`
+	Message   string     `json:"message"`
+	Referrers []Referrer `json:"referrers"`
+}
+
+// Referrer mirrors crawler.Referrer.
+type Referrer struct {
+	Source string `json:"source"`
+	Text   string `json:"text,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// Structure mirrors crawler.Structure.
+type Structure struct {
+	Pages        []PageStructure `json:"pages"`
+	DeadEnds     []string        `json:"dead_ends"`
+	RedirectOnly []string        `json:"redirect_only"`
+	Orphans      []string        `json:"orphans"`
+}
+
+// PageStructure mirrors crawler.PageStructure.
+type PageStructure struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Inbound  int    `json:"inbound"`
+	Outbound int    `json:"outbound"`
+}
+
+// Duplicate mirrors crawler.DuplicateCluster.
+type Duplicate struct {
+	Kind          string   `json:"kind"`
+	ContentSHA256 string   `json:"content_sha256,omitempty"`
+	Pages         []string `json:"pages"`
+}
+
+// IgnoredError mirrors crawler.IgnoredError.
+type IgnoredError struct {
+	Error
+	Rule      string `json:"rule"`
+	Reason    string `json:"reason,omitempty"`
+	Baselined bool   `json:"baselined,omitempty"`
+}
+
+// Skip mirrors crawler.Skip.
+type Skip struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// Error mirrors crawler.Error.
+type Error struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Type     string `json:"type"`
+	Status   int    `json:"status,omitempty"`
+	Message  string `json:"message"`
+	Severity string `json:"severity,omitempty"`
+	Link     *Link  `json:"link,omitempty"`
+}
+
+// New converts a crawler report into a Document with deterministic ordering.
+func New(r *crawler.Report) *Document {
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		Pages:         []Page{},
+		Errors:        []Error{},
+		BrokenTargets: []BrokenTarget{},
+	}
+	if r == nil {
+		return doc
+	}
+	doc.StartedAt = r.StartedAt.UTC()
+	doc.FinishedAt = r.FinishedAt.UTC()
+	doc.Partial = r.Partial
+	doc.Cancelled = r.Cancelled
+	doc.Stats = newStats(r.Stats)
+	doc.Unvisited = append([]string(nil), r.Unvisited...)
+	sort.Strings(doc.Unvisited)
+	doc.NoIndex = append([]string(nil), r.NoIndex...)
+	sort.Strings(doc.NoIndex)
+	for _, skip := range r.Skipped {
+		doc.Skipped = append(doc.Skipped, Skip{URL: skip.URL, Reason: string(skip.Reason)})
+	}
+	sort.SliceStable(doc.Skipped, func(i, j int) bool { return doc.Skipped[i].URL < doc.Skipped[j].URL })
+
+	keys := make([]string, 0, len(r.Pages))
+	for key := range r.Pages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if page := r.Pages[key]; page != nil {
+			doc.Pages = append(doc.Pages, newPage(page))
+		}
+	}
+
+	doc.Errors = newErrors(r.Errors)
+	if doc.Errors == nil {
+		doc.Errors = []Error{}
+	}
+	doc.Baselined = newErrors(r.Baselined)
+	doc.BaselineFixed = newErrors(r.BaselineFixed)
+	doc.BaselineApplied = r.BaselineApplied
+	for _, ignored := range r.Ignored {
+		doc.Ignored = append(doc.Ignored, IgnoredError{Error: newError(ignored.Error), Rule: ignored.Rule, Reason: ignored.Reason, Baselined: ignored.Baselined})
+	}
+	sort.SliceStable(doc.Ignored, func(i, j int) bool { return errorLess(doc.Ignored[i].Error, doc.Ignored[j].Error) })
+
+	for _, target := range r.BrokenTargets() {
+		bt := BrokenTarget{
+			URL:       target.URL,
+			Type:      target.Type,
+			Status:    target.Status,
+			Message:   target.Message,
+			Referrers: make([]Referrer, 0, len(target.Referrers)),
+		}
+		for _, ref := range target.Referrers {
+			bt.Referrers = append(bt.Referrers, Referrer{Source: ref.Source, Text: ref.Text, Line: ref.Line})
+		}
+		doc.BrokenTargets = append(doc.BrokenTargets, bt)
+	}
+	doc.Structure = newStructure(r.Structure)
+	for _, f := range r.Findings {
+		doc.Findings = append(doc.Findings, Finding{
+			Check:    f.Check,
+			Rule:     f.Rule,
+			URL:      f.URL,
+			Message:  f.Message,
+			Snippet:  f.Snippet,
+			Line:     f.Line,
+			Severity: string(f.Severity),
+		})
+	}
+	for _, host := range r.TLS {
+		var notAfter *time.Time
+		if !host.NotAfter.IsZero() {
+			t := host.NotAfter.UTC()
+			notAfter = &t
+		}
+		doc.TLS = append(doc.TLS, TLSHost{
+			Host:             host.Host,
+			Version:          host.Version,
+			Subject:          host.Subject,
+			Issuer:           host.Issuer,
+			NotAfter:         notAfter,
+			DNSNames:         append([]string(nil), host.DNSNames...),
+			SelfSigned:       host.SelfSigned,
+			HostnameMismatch: host.HostnameMismatch,
+			VersionTooOld:    host.VersionTooOld,
+			Error:            host.Error,
+		})
+	}
+	for _, group := range r.Headers {
+		doc.Headers = append(doc.Headers, HeaderGroup{Host: group.Host, Prefix: group.Prefix, Pages: group.Pages, Issues: group.Issues})
+	}
+	for _, group := range r.Performance {
+		doc.Performance = append(doc.Performance, Performance{
+			Host:        group.Host,
+			Prefix:      group.Prefix,
+			Pages:       group.Pages,
+			TTFBMS:      latencyPercentiles(group.TTFB),
+			RetrievedMS: latencyPercentiles(group.Retrieved),
+			SizeBytes:   Percentiles(group.Size),
+		})
+	}
+	for _, cluster := range r.Duplicates {
+		doc.Duplicates = append(doc.Duplicates, Duplicate{
+			Kind:          string(cluster.Kind),
+			ContentSHA256: cluster.ContentHash,
+			Pages:         append([]string{}, cluster.Pages...),
+		})
+	}
+	return doc
+}
+
+func latencyPercentiles(p crawler.LatencyPercentiles) Percentiles {
+	return Percentiles{P50: p.P50.Milliseconds(), P90: p.P90.Milliseconds(), P99: p.P99.Milliseconds()}
+}
+
+func newStructure(s *crawler.Structure) *Structure {
+	if s == nil {
+		return nil
+	}
+	structure := &Structure{
+		Pages:        make([]PageStructure, 0, len(s.Pages)),
+		DeadEnds:     append([]string{}, s.DeadEnds...),
+		RedirectOnly: append([]string{}, s.RedirectOnly...),
+		Orphans:      append([]string{}, s.Orphans...),
+	}
+	for _, page := range s.Pages {
+		structure.Pages = append(structure.Pages, PageStructure{URL: page.URL, Depth: page.Depth, Inbound: page.Inbound, Outbound: page.Outbound})
+	}
+	return structure
+}
+
+func newStats(s crawler.Stats) Stats {
+	return Stats{
+		PagesVisited:         s.PagesVisited,
+		UniqueInternalPages:  s.UniqueInternalPages,
+		UniqueExternalLinks:  s.UniqueExternalLinks,
+		TotalInternalLinks:   s.TotalInternalLinks,
+		TotalExternalLinks:   s.TotalExternalLinks,
+		ExternalLinksChecked: s.ExternalLinksChecked,
+		DurationMS:           s.Duration.Milliseconds(),
+		SkippedByCache:       s.SkippedByCache,
+		SkippedByRobots:      s.SkippedByRobots,
+		SkippedByExtension:   s.SkippedByExtension,
+		SkippedByLimit:       s.SkippedByLimit,
+		SkippedByDepth:       s.SkippedByDepth,
+		SkippedByNofollow:    s.SkippedByNofollow,
+		SkippedByRelNofollow: s.SkippedByRelNofollow,
+	}
+}
+
+func newPage(p *crawler.PageReport) Page {
+	page := Page{
+		URL:                   p.URL,
+		RedirectURL:           p.RedirectURL,
+		Depth:                 p.Depth,
+		Status:                p.Status,
+		ContentType:           p.ContentType,
+		Error:                 p.Error,
+		RetrievedMS:           p.Retrieved.Milliseconds(),
+		MarkdownPath:          p.MarkdownPath,
+		MarkdownSkippedReason: p.MarkdownSkippedReason,
+		ContentSHA256:         p.ContentHash,
+		SizeBytes:             p.Size,
+		NoIndex:               p.NoIndex,
+		NoFollow:              p.NoFollow,
+		Links:                 make([]Link, 0, len(p.Links)),
+	}
+	if p.SEO != nil {
+		page.SEO = &SEOMetadata{
+			Title:       p.SEO.Title,
+			Description: p.SEO.Description,
+			Canonical:   p.SEO.Canonical,
+			H1Count:     p.SEO.H1Count,
+			Lang:        p.SEO.Lang,
+			OpenGraph:   p.SEO.OpenGraph,
+		}
+	}
+	if p.Headers != nil {
+		page.Headers = &SecurityHeaders{
+			StrictTransportSecurity: p.Headers.StrictTransportSecurity,
+			ContentSecurityPolicy:   p.Headers.ContentSecurityPolicy,
+			ContentTypeOptions:      p.Headers.ContentTypeOptions,
+			FrameOptions:            p.Headers.FrameOptions,
+			ReferrerPolicy:          p.Headers.ReferrerPolicy,
+		}
+		for _, cookie := range p.Headers.Cookies {
+			page.Headers.Cookies = append(page.Headers.Cookies, CookieFlags(cookie))
+		}
+	}
+	if p.Timing != nil {
+		page.Timing = &Timing{
+			DNSMS:      p.Timing.DNS.Milliseconds(),
+			ConnectMS:  p.Timing.Connect.Milliseconds(),
+			TLSMS:      p.Timing.TLS.Milliseconds(),
+			TTFBMS:     p.Timing.TTFB.Milliseconds(),
+			DownloadMS: p.Timing.Download.Milliseconds(),
+		}
+	}
+	if p.Relations != nil {
+		page.Relations = &LinkRelations{
+			Canonical: p.Relations.Canonical,
+			Next:      p.Relations.Next,
+			Prev:      p.Relations.Prev,
+			AMPHTML:   p.Relations.AMPHTML,
+			Srcset:    p.Relations.Srcset,
+		}
+		for _, alt := range p.Relations.Alternates {
+			page.Relations.Alternates = append(page.Relations.Alternates, Alternate(alt))
+		}
+	}
+	if p.SimHash != 0 {
+		page.SimHash = fmt.Sprintf("%016x", p.SimHash)
+	}
+	for _, link := range p.Links {
+		page.Links = append(page.Links, newLink(link))
+	}
+	return page
+}
+
+func newLink(l crawler.Link) Link {
+	return Link{
+		URL:     l.URL,
+		Type:    string(l.Type),
+		Element: l.Element,
+		Text:    l.Text,
+		Line:    l.Line,
+		Page:    l.Page,
+		Rel:     append([]string(nil), l.Rel...),
+		Target:  l.Target,
+		Title:   l.Title,
+	}
+}
+
+// newErrors converts and sorts errs, returning nil for an empty slice.
+func newErrors(errs []crawler.Error) []Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	converted := make([]Error, 0, len(errs))
+	for _, err := range errs {
+		converted = append(converted, newError(err))
+	}
+	sort.SliceStable(converted, func(i, j int) bool { return errorLess(converted[i], converted[j]) })
+	return converted
+}
+
+func newError(err crawler.Error) Error {
+	e := Error{
+		Source:   err.Source,
+		Target:   err.Target,
+		Type:     err.Type,
+		Status:   err.Status,
+		Message:  err.Message,
+		Severity: string(err.Severity),
+	}
+	if err.Link != nil {
+		link := newLink(*err.Link)
+		e.Link = &link
+	}
+	return e
+}
+
+func errorLess(a, b Error) bool {
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	if a.Target != b.Target {
+		return a.Target < b.Target
+	}
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	if a.Status != b.Status {
+		return a.Status < b.Status
+	}
+	return a.Message < b.Message
+}
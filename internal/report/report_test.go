@@ -0,0 +1,613 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"linkcheck/internal/crawler"
+)
+
+func sampleReport() *crawler.Report {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &crawler.Report{
+		Pages: map[string]*crawler.PageReport{
+			"https://example.test/b": {
+				URL:       "https://example.test/b",
+				Depth:     1,
+				Status:    404,
+				Error:     "status 404",
+				Retrieved: 120 * time.Millisecond,
+			},
+			"https://example.test/": {
+				URL:       "https://example.test/",
+				Status:    200,
+				Retrieved: 45 * time.Millisecond,
+				Links: []crawler.Link{
+					{URL: "https://example.test/b", Type: crawler.LinkTypeInternal, Text: "B"},
+					{URL: "https://other.test/", Type: crawler.LinkTypeExternal},
+				},
+			},
+		},
+		Errors: []crawler.Error{
+			{Source: "https://example.test/", Target: "https://other.test/", Type: "http", Message: "status 500", Status: 500},
+			{Source: "https://example.test/b", Target: "https://example.test/b", Type: "http", Message: "status 404", Status: 404},
+			{Source: "https://example.test/", Target: "https://example.test/b", Type: "http", Message: "status 404", Status: 404, Link: &crawler.Link{
+				URL:     "https://example.test/b",
+				Type:    crawler.LinkTypeInternal,
+				Element: crawler.LinkElementAnchor,
+				Text:    "Download the SDK",
+				Rel:     []string{"nofollow", "noopener"},
+			}},
+		},
+		Stats:      crawler.Stats{PagesVisited: 2, Duration: 1500 * time.Millisecond},
+		StartedAt:  started,
+		FinishedAt: started.Add(1500 * time.Millisecond),
+	}
+}
+
+func TestNewOrdersPagesAndErrors(t *testing.T) {
+	doc := New(sampleReport())
+	if doc.SchemaVersion != SchemaVersion {
+		t.Fatalf("unexpected schema version %d", doc.SchemaVersion)
+	}
+	if got := []string{doc.Pages[0].URL, doc.Pages[1].URL}; got[0] != "https://example.test/" || got[1] != "https://example.test/b" {
+		t.Fatalf("pages not sorted by URL: %v", got)
+	}
+	wantTargets := []string{"https://example.test/b", "https://other.test/", "https://example.test/b"}
+	for i, want := range wantTargets {
+		if doc.Errors[i].Target != want {
+			t.Fatalf("error %d: expected target %q, got %q", i, want, doc.Errors[i].Target)
+		}
+	}
+	if doc.Pages[1].RetrievedMS != 120 {
+		t.Fatalf("expected retrieved_ms 120, got %d", doc.Pages[1].RetrievedMS)
+	}
+}
+
+func TestWriteJSONIsStable(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := WriteJSON(&first, sampleReport()); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	if err := WriteJSON(&second, sampleReport()); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Fatal("expected identical output for identical reports")
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(first.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if decoded["schema_version"].(float64) != SchemaVersion {
+		t.Fatalf("missing schema version: %v", decoded)
+	}
+	stats := decoded["stats"].(map[string]any)
+	if stats["duration_ms"].(float64) != 1500 {
+		t.Fatalf("unexpected duration: %v", stats)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var errorsOut, pagesOut bytes.Buffer
+	if err := WriteErrorsCSV(&errorsOut, sampleReport()); err != nil {
+		t.Fatalf("write errors csv: %v", err)
+	}
+	if err := WritePagesCSV(&pagesOut, sampleReport()); err != nil {
+		t.Fatalf("write pages csv: %v", err)
+	}
+
+	errorRows, err := csv.NewReader(&errorsOut).ReadAll()
+	if err != nil {
+		t.Fatalf("parse errors csv: %v", err)
+	}
+	if len(errorRows) != 4 {
+		t.Fatalf("expected header and three errors, got %d rows", len(errorRows))
+	}
+	if strings.Join(errorRows[1], ",") != "https://example.test/,https://example.test/b,http,404,status 404,Download the SDK,nofollow noopener," {
+		t.Fatalf("unexpected first error row: %v", errorRows[1])
+	}
+
+	pageRows, err := csv.NewReader(&pagesOut).ReadAll()
+	if err != nil {
+		t.Fatalf("parse pages csv: %v", err)
+	}
+	if len(pageRows) != 3 {
+		t.Fatalf("expected header and two pages, got %d rows", len(pageRows))
+	}
+	if got := pageRows[1]; got[0] != "https://example.test/" || got[4] != "1" || got[5] != "1" {
+		t.Fatalf("unexpected page row: %v", got)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteNDJSON(&out, sampleReport()); err != nil {
+		t.Fatalf("write ndjson: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected summary, two pages and three errors, got %d lines", len(lines))
+	}
+	wantKinds := []string{RecordSummary, RecordPage, RecordPage, RecordError, RecordError, RecordError}
+	for i, line := range lines {
+		var record struct {
+			Record string `json:"record"`
+			Status int    `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if record.Record != wantKinds[i] {
+			t.Fatalf("line %d: expected record %q, got %q", i, wantKinds[i], record.Record)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := sampleReport()
+	r.Pages["https://example.test/private/"] = &crawler.PageReport{URL: "https://example.test/private/", Error: "blocked by robots.txt"}
+	r.Skipped = []crawler.Skip{
+		{URL: "https://example.test/private/", Reason: crawler.SkipReasonRobots},
+		{URL: "https://example.test/docs/manual.pdf", Reason: crawler.SkipReasonExtension},
+	}
+
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, r, JUnitOptions{}); err != nil {
+		t.Fatalf("write junit: %v", err)
+	}
+
+	var decoded junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode junit: %v", err)
+	}
+	if decoded.Tests != 4 || decoded.Failures != 2 || decoded.Skipped != 2 {
+		t.Fatalf("unexpected totals: tests=%d failures=%d skipped=%d", decoded.Tests, decoded.Failures, decoded.Skipped)
+	}
+	if len(decoded.Suites) != 1 || decoded.Suites[0].Name != "example.test" {
+		t.Fatalf("expected a single host suite, got %+v", decoded.Suites)
+	}
+	var home *junitTestCase
+	for i := range decoded.Suites[0].Cases {
+		if decoded.Suites[0].Cases[i].Name == "https://example.test/" {
+			home = &decoded.Suites[0].Cases[i]
+		}
+	}
+	if home == nil {
+		t.Fatal("missing testcase for start page")
+	}
+	if len(home.Failures) != 2 || home.Time != "0.045" {
+		t.Fatalf("unexpected start page testcase: %+v", home)
+	}
+	if !strings.Contains(home.Failures[0].Body, "target: https://example.test/b") || home.Failures[0].Type != "http" {
+		t.Fatalf("unexpected failure: %+v", home.Failures[0])
+	}
+}
+
+func TestJUnitSuiteNameByPathPrefix(t *testing.T) {
+	opts := JUnitOptions{GroupBy: GroupByPathPrefix, PrefixDepth: 1}
+	cases := map[string]string{
+		"https://example.test/":                   "example.test/",
+		"https://example.test/about.html":         "example.test/",
+		"https://example.test/docs/":              "example.test/docs",
+		"https://example.test/docs/api/page.html": "example.test/docs",
+	}
+	for input, want := range cases {
+		if got := junitSuiteName(input, opts); got != want {
+			t.Errorf("junitSuiteName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	opts := SARIFOptions{PathMappings: []SARIFPathMapping{{
+		URLPrefix:  "https://example.test/",
+		PathPrefix: "content/",
+		Extension:  ".md",
+	}}}
+
+	var out bytes.Buffer
+	if err := WriteSARIF(&out, sampleReport(), opts); err != nil {
+		t.Fatalf("write sarif: %v", err)
+	}
+
+	var decoded sarifLog
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode sarif: %v", err)
+	}
+	if decoded.Version != "2.1.0" || len(decoded.Runs) != 1 {
+		t.Fatalf("unexpected sarif envelope: %+v", decoded)
+	}
+	run := decoded.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "http" {
+		t.Fatalf("expected a single http rule, got %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("expected three results, got %d", len(run.Results))
+	}
+	first := run.Results[0]
+	if first.Level != "error" || first.Properties.Target != "https://example.test/b" || first.Properties.LinkText != "Download the SDK" {
+		t.Fatalf("unexpected first result: %+v", first)
+	}
+	location := first.Locations[0].PhysicalLocation.ArtifactLocation
+	if location.URI != "content/index.md" || location.URIBaseID != "%SRCROOT%" {
+		t.Fatalf("unexpected mapped location: %+v", location)
+	}
+	if got := run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI; got != "content/b.md" {
+		t.Fatalf("expected page path to be rewritten, got %q", got)
+	}
+
+	docs := SARIFOptions{PathMappings: []SARIFPathMapping{{URLPrefix: "https://example.test/docs", PathPrefix: "docs"}}}
+	for pageURL, want := range map[string]string{
+		"https://example.test/docs":          "docs/index.html",
+		"https://example.test/docs/a.html":   "docs/a.html",
+		"https://example.test/docs?lang=de":  "docs/index.html",
+		"https://example.test/docs-old/a":    "https://example.test/docs-old/a",
+		"https://example.test/documentation": "https://example.test/documentation",
+	} {
+		if got := docs.artifactLocation(pageURL).URI; got != want {
+			t.Errorf("artifactLocation(%q) = %q, want %q", pageURL, got, want)
+		}
+	}
+}
+
+func TestWriteSARIFBaselineState(t *testing.T) {
+	states := func(r *crawler.Report) map[string]string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := WriteSARIF(&out, r, SARIFOptions{}); err != nil {
+			t.Fatalf("write sarif: %v", err)
+		}
+		var decoded sarifLog
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+			t.Fatalf("decode sarif: %v", err)
+		}
+		got := map[string]string{}
+		for _, result := range decoded.Runs[0].Results {
+			got[result.Properties.Target] = result.BaselineState
+		}
+		return got
+	}
+
+	r := sampleReport()
+	r.Errors = []crawler.Error{{Source: "https://example.test/", Target: "https://example.test/new", Type: "http", Status: 404}}
+	r.Baselined = []crawler.Error{{Source: "https://example.test/", Target: "https://example.test/known", Type: "http", Status: 404}}
+	r.Ignored = []crawler.IgnoredError{
+		{Error: crawler.Error{Source: "https://example.test/", Target: "https://example.test/muted", Type: "http", Status: 404}, Rule: "*/muted"},
+		{Error: crawler.Error{Source: "https://example.test/", Target: "https://example.test/muted-known", Type: "http", Status: 404}, Rule: "*/muted-known", Baselined: true},
+	}
+	if got := states(r); got["https://example.test/new"] != "" || got["https://example.test/known"] != "" {
+		t.Fatalf("expected no baseline state without a baseline, got %v", got)
+	}
+
+	r.BaselineApplied = true
+	want := map[string]string{
+		"https://example.test/new":         "new",
+		"https://example.test/known":       "unchanged",
+		"https://example.test/muted":       "new",
+		"https://example.test/muted-known": "unchanged",
+	}
+	if got := states(r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected baseline states: %v", got)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	r := sampleReport()
+	r.Errors = append(r.Errors, crawler.Error{Source: "https://example.test/<script>", Target: "https://other.test/", Type: "http", Message: "status 500", Status: 500})
+	r.Pages["https://example.test/docs/intro"] = &crawler.PageReport{
+		URL:    "https://example.test/docs/intro",
+		Depth:  1,
+		Status: 200,
+		Links: []crawler.Link{
+			{URL: "https://example.test/docs/setup", Type: crawler.LinkTypeInternal},
+			{URL: "https://example.test/b", Type: crawler.LinkTypeInternal, Text: "Back"},
+		},
+	}
+	r.StartURL = "https://example.test/"
+	r.Structure = crawler.AnalyzeStructure(r)
+
+	var out bytes.Buffer
+	if err := WriteHTML(&out, r); err != nil {
+		t.Fatalf("write html: %v", err)
+	}
+	content := out.String()
+	for _, want := range []string{"<!doctype html>", "Broken links by target", "Skipped by depth", "2 page(s)", "https://other.test/", "Site structure", "Link graph", "<li>example.test/ (1) &ldquo;Back&rdquo;</li>"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in html report", want)
+		}
+	}
+	if strings.Contains(content, "https://example.test/<script>") {
+		t.Fatal("expected URLs to be escaped")
+	}
+	if strings.Contains(content, "src=\"http") || strings.Contains(content, "href=\"http") {
+		t.Fatal("expected no external assets")
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := WriteJSON(&encoded, sampleReport()); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	before, err := ReadJSON(&encoded)
+	if err != nil {
+		t.Fatalf("read json: %v", err)
+	}
+
+	next := sampleReport()
+	delete(next.Pages, "https://example.test/b")
+	next.Pages["https://example.test/"].Status = 500
+	next.Pages["https://example.test/"].RedirectURL = "https://example.test/home"
+	next.Pages["https://example.test/"].Retrieved = 900 * time.Millisecond
+	next.Pages["https://example.test/c"] = &crawler.PageReport{URL: "https://example.test/c", Status: 200}
+	next.Errors = []crawler.Error{
+		{Source: "https://example.test/", Target: "https://example.test/b", Type: "http", Message: "status 404", Status: 404},
+		{Source: "https://example.test/c", Target: "https://gone.test/", Type: "request", Message: "no such host"},
+	}
+	after := New(next)
+
+	d := Compare(before, after, DiffOptions{})
+	if len(d.NewErrors) != 1 || d.NewErrors[0].Target != "https://gone.test/" {
+		t.Fatalf("unexpected new errors: %+v", d.NewErrors)
+	}
+	if len(d.FixedErrors) != 2 {
+		t.Fatalf("expected two fixed errors, got %+v", d.FixedErrors)
+	}
+	if len(d.AddedPages) != 1 || len(d.RemovedPages) != 1 {
+		t.Fatalf("unexpected page changes: added=%v removed=%v", d.AddedPages, d.RemovedPages)
+	}
+	if len(d.StatusChanges) != 1 || !d.StatusChanges[0].Regression {
+		t.Fatalf("expected status regression, got %+v", d.StatusChanges)
+	}
+	if len(d.RedirectChanges) != 1 || d.RedirectChanges[0].New != "https://example.test/home" {
+		t.Fatalf("expected redirect change, got %+v", d.RedirectChanges)
+	}
+	if len(d.LatencyRegressions) != 1 || d.LatencyRegressions[0].NewMS != 900 {
+		t.Fatalf("expected latency regression, got %+v", d.LatencyRegressions)
+	}
+	if d.ExitCode() != 1 {
+		t.Fatal("expected non-zero exit code for regressions")
+	}
+
+	var text bytes.Buffer
+	if err := WriteDiffText(&text, d); err != nil {
+		t.Fatalf("write diff text: %v", err)
+	}
+	if !strings.Contains(text.String(), "+ https://example.test/c -> https://gone.test/") {
+		t.Fatalf("unexpected diff text: %s", text.String())
+	}
+	var payload bytes.Buffer
+	if err := WriteDiffJSON(&payload, d); err != nil {
+		t.Fatalf("write diff json: %v", err)
+	}
+	if !strings.Contains(payload.String(), `"regressions": true`) {
+		t.Fatalf("unexpected diff json: %s", payload.String())
+	}
+
+	if Compare(after, after, DiffOptions{}).ExitCode() != 0 {
+		t.Fatal("expected identical reports to produce no regressions")
+	}
+}
+
+func TestCompareLatencyAndUnfetchedPages(t *testing.T) {
+	before := &Document{Pages: []Page{
+		{URL: "https://example.test/slow", Status: 200, RetrievedMS: 100},
+		{URL: "https://example.test/limited", Status: 200},
+		{URL: "https://example.test/blocked", Error: "blocked by robots.txt"},
+	}}
+	after := &Document{Pages: []Page{
+		{URL: "https://example.test/slow", Status: 200, RetrievedMS: 900},
+		{URL: "https://example.test/limited", Error: "rate limit reached"},
+		{URL: "https://example.test/blocked", Status: 404},
+	}}
+
+	d := Compare(before, after, DiffOptions{})
+	if len(d.LatencyRegressions) != 1 {
+		t.Fatalf("expected latency regression to be reported, got %+v", d.LatencyRegressions)
+	}
+	if len(d.StatusChanges) != 2 {
+		t.Fatalf("expected two status changes, got %+v", d.StatusChanges)
+	}
+	for _, change := range d.StatusChanges {
+		if change.Regression {
+			t.Fatalf("unfetched page counted as regression: %+v", change)
+		}
+	}
+	if d.ExitCode() != 0 {
+		t.Fatal("expected latency alone not to fail the diff")
+	}
+	if Compare(before, after, DiffOptions{FailOnLatency: true}).ExitCode() != 1 {
+		t.Fatal("expected FailOnLatency to fail the diff")
+	}
+}
+
+func TestReadJSONRejectsUnknownSchema(t *testing.T) {
+	if _, err := ReadJSON(strings.NewReader(`{"schema_version": 99}`)); err == nil {
+		t.Fatal("expected error for unsupported schema version")
+	}
+}
+
+func TestNewIncludesStructure(t *testing.T) {
+	r := sampleReport()
+	r.StartURL = "https://example.test/"
+	r.Structure = crawler.AnalyzeStructure(r)
+	doc := New(r)
+	if doc.Structure == nil || len(doc.Structure.Pages) != 2 {
+		t.Fatalf("unexpected structure: %+v", doc.Structure)
+	}
+	if page := doc.Structure.Pages[1]; page.URL != "https://example.test/b" || page.Depth != 1 || page.Inbound != 1 {
+		t.Fatalf("unexpected page structure: %+v", page)
+	}
+	if doc.Structure.DeadEnds == nil || len(doc.Structure.DeadEnds) != 0 {
+		t.Fatalf("expected empty dead ends for failing pages, got %v", doc.Structure.DeadEnds)
+	}
+	if New(sampleReport()).Structure != nil {
+		t.Fatal("expected no structure without analysis")
+	}
+}
+
+func TestNewIncludesDuplicates(t *testing.T) {
+	r := sampleReport()
+	r.Pages["https://example.test/"].ContentHash = "abc"
+	r.Pages["https://example.test/"].SimHash = 0xbeef
+	r.Duplicates = []crawler.DuplicateCluster{{Kind: crawler.DuplicateExact, ContentHash: "abc", Pages: []string{"https://example.test/", "https://example.test/?page=1"}}}
+	doc := New(r)
+	if page := doc.Pages[0]; page.ContentSHA256 != "abc" || page.SimHash != "000000000000beef" {
+		t.Fatalf("unexpected fingerprints: %+v", page)
+	}
+	if len(doc.Duplicates) != 1 || doc.Duplicates[0].Kind != "exact" || len(doc.Duplicates[0].Pages) != 2 {
+		t.Fatalf("unexpected duplicates: %+v", doc.Duplicates)
+	}
+
+	var out bytes.Buffer
+	if err := WriteHTML(&out, r); err != nil {
+		t.Fatalf("write html: %v", err)
+	}
+	if !strings.Contains(out.String(), "Duplicate content") || !strings.Contains(out.String(), "https://example.test/?page=1") {
+		t.Fatal("expected duplicate section in html report")
+	}
+}
+
+func TestFindingsInReports(t *testing.T) {
+	r := sampleReport()
+	r.Pages["https://example.test/"].SEO = &crawler.SEOMetadata{Title: "Home", H1Count: 2}
+	r.Findings = []crawler.Finding{{Check: crawler.CheckSEO, Rule: "h1-multiple", URL: "https://example.test/", Message: "page has 2 <h1> headings", Line: 7, Severity: crawler.SeverityWarning}}
+
+	doc := New(r)
+	if seo := doc.Pages[0].SEO; seo == nil || seo.Title != "Home" || seo.H1Count != 2 {
+		t.Fatalf("unexpected SEO metadata: %+v", seo)
+	}
+
+	var nd bytes.Buffer
+	if err := WriteNDJSON(&nd, r); err != nil {
+		t.Fatalf("write ndjson: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(nd.String()), "\n")
+	var last map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("decode last record: %v", err)
+	}
+	if last["record"] != RecordFinding || last["rule"] != "h1-multiple" || last["severity"] != "warning" || last["line"].(float64) != 7 {
+		t.Fatalf("unexpected finding record: %v", last)
+	}
+
+	var out bytes.Buffer
+	if err := WriteHTML(&out, r); err != nil {
+		t.Fatalf("write html: %v", err)
+	}
+	if !strings.Contains(out.String(), "Audit findings") || !strings.Contains(out.String(), "page has 2 &lt;h1&gt; headings") {
+		t.Fatal("expected findings section in html report")
+	}
+}
+
+func TestTLSHostsOmitUnknownExpiry(t *testing.T) {
+	r := sampleReport()
+	expiry := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	r.TLS = []crawler.TLSHost{
+		{Host: "good.test", Version: "TLS 1.3", NotAfter: expiry},
+		{Host: "broken.test", Error: "handshake failed"},
+	}
+
+	var encoded bytes.Buffer
+	if err := WriteJSON(&encoded, r); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	var doc struct {
+		TLS []map[string]any `json:"tls"`
+	}
+	if err := json.Unmarshal(encoded.Bytes(), &doc); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if len(doc.TLS) != 2 {
+		t.Fatalf("expected two TLS hosts, got %v", doc.TLS)
+	}
+	if doc.TLS[0]["not_after"] != "2030-01-02T00:00:00Z" {
+		t.Fatalf("expected expiry of the good host, got %v", doc.TLS[0])
+	}
+	if _, ok := doc.TLS[1]["not_after"]; ok {
+		t.Fatalf("expected no expiry for the failed host, got %v", doc.TLS[1])
+	}
+}
+
+func TestNewGraph(t *testing.T) {
+	g := NewGraph(sampleReport(), GraphOptions{IncludeExternal: true})
+	if len(g.Nodes) != 3 || len(g.Edges) != 2 {
+		t.Fatalf("unexpected graph: %+v", g)
+	}
+	b := g.Nodes[1]
+	if b.ID != "https://example.test/b" || b.Status != 404 || b.Depth != 1 || b.Pages != 1 {
+		t.Fatalf("unexpected node: %+v", b)
+	}
+	other := g.Nodes[2]
+	if other.Type != "external" || other.Status != 500 || other.Depth != -1 {
+		t.Fatalf("unexpected external node: %+v", other)
+	}
+	if e := g.Edges[0]; e.Target != "https://example.test/b" || e.Text != "B" || e.Count != 1 {
+		t.Fatalf("unexpected edge: %+v", e)
+	}
+
+	collapsed := NewGraph(sampleReport(), GraphOptions{CollapseDepth: 1})
+	if len(collapsed.Nodes) != 1 || collapsed.Nodes[0].ID != "example.test/" || collapsed.Nodes[0].Pages != 2 {
+		t.Fatalf("unexpected collapsed nodes: %+v", collapsed.Nodes)
+	}
+	if len(collapsed.Edges) != 0 {
+		t.Fatalf("expected links inside a collapsed node to be dropped, got %+v", collapsed.Edges)
+	}
+}
+
+func TestWriteGraphFormats(t *testing.T) {
+	var dot bytes.Buffer
+	if err := WriteDOT(&dot, sampleReport(), GraphOptions{}); err != nil {
+		t.Fatalf("write dot: %v", err)
+	}
+	for _, want := range []string{
+		"digraph linkcheck {",
+		`"https://example.test/b" [label="https://example.test/b", type="internal", depth=1, status=404, color=red];`,
+		`"https://example.test/" -> "https://example.test/b" [count=1, label="B"];`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Fatalf("dot output missing %q:\n%s", want, dot.String())
+		}
+	}
+
+	var graphml bytes.Buffer
+	if err := WriteGraphML(&graphml, sampleReport(), GraphOptions{}); err != nil {
+		t.Fatalf("write graphml: %v", err)
+	}
+	var parsed struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(graphml.Bytes(), &parsed); err != nil {
+		t.Fatalf("parse graphml: %v", err)
+	}
+	if len(parsed.Graph.Nodes) != 2 || len(parsed.Graph.Edges) != 1 || parsed.Graph.Edges[0].Target != "n1" {
+		t.Fatalf("unexpected graphml: %s", graphml.String())
+	}
+
+	var js bytes.Buffer
+	if err := WriteGraphJSON(&js, sampleReport(), GraphOptions{}); err != nil {
+		t.Fatalf("write graph json: %v", err)
+	}
+	var decoded Graph
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode graph json: %v", err)
+	}
+	if len(decoded.Nodes) != 2 || len(decoded.Edges) != 1 {
+		t.Fatalf("unexpected graph json: %s", js.String())
+	}
+}
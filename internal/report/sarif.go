@@ -0,0 +1,326 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"linkcheck/internal/crawler"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifSrcRoot   = "%SRCROOT%"
+)
+
+// SARIFOptions configures WriteSARIF.
+type SARIFOptions struct {
+	// PathMappings rewrite source page URLs into repository-relative paths so
+	// that findings attach to the file that produced the page. The first
+	// mapping whose URLPrefix matches wins; unmapped pages keep their URL.
+	PathMappings []SARIFPathMapping
+}
+
+// SARIFPathMapping rewrites URLs under URLPrefix into paths starting with
+// PathPrefix. URLPrefix ends on a path segment boundary. Extension, when set,
+// replaces the page's extension (for example ".html" becomes ".md"), and
+// IndexName names the file used for directory URLs ending in "/" (default
+// "index").
+type SARIFPathMapping struct {
+	URLPrefix  string
+	PathPrefix string
+	Extension  string
+	IndexName  string
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             sarifMessage       `json:"message"`
+	Locations           []sarifLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints"`
+	Suppressions        []sarifSuppression `json:"suppressions,omitempty"`
+	BaselineState       string             `json:"baselineState,omitempty"`
+	Properties          sarifProperties    `json:"properties"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifProperties struct {
+	Source   string   `json:"source"`
+	Target   string   `json:"target"`
+	Status   int      `json:"status,omitempty"`
+	LinkText string   `json:"linkText,omitempty"`
+	LinkRel  []string `json:"linkRel,omitempty"`
+}
+
+type sarifRuleInfo struct {
+	name        string
+	description string
+	level       string
+}
+
+var sarifRules = map[string]sarifRuleInfo{
+	"http":     {"BrokenLink", "Link target returned an HTTP error status.", "error"},
+	"request":  {"RequestFailed", "Link target could not be requested.", "error"},
+	"read":     {"ReadFailed", "Response body could not be read.", "warning"},
+	"parse":    {"InvalidURL", "Link URL could not be parsed.", "error"},
+	"markdown": {"MarkdownExportFailed", "Page could not be exported as markdown.", "note"},
+	"rate":     {"RateLimited", "Request was dropped by the rate limiter.", "note"},
+}
+
+// WriteSARIF writes the report's errors as a SARIF 2.1.0 log. Each error type
+// becomes a rule and each error a result located at its source page. Errors
+// suppressed by an ignore rule or a baseline are included with a suppression
+// so that code-scanning dashboards show them as dismissed. When a baseline was
+// applied, every result carries a baselineState: "unchanged" for errors the
+// baseline records and "new" for the rest.
+func WriteSARIF(w io.Writer, r *crawler.Report, opts SARIFOptions) error {
+	doc := New(r)
+
+	type finding struct {
+		err           Error
+		suppression   *sarifSuppression
+		baselineState string
+	}
+	state := func(known bool) string {
+		switch {
+		case !doc.BaselineApplied:
+			return ""
+		case known:
+			return "unchanged"
+		default:
+			return "new"
+		}
+	}
+	findings := make([]finding, 0, len(doc.Errors)+len(doc.Ignored)+len(doc.Baselined))
+	for _, e := range doc.Errors {
+		findings = append(findings, finding{err: e, baselineState: state(false)})
+	}
+	for _, ignored := range doc.Ignored {
+		justification := ignored.Reason
+		if justification == "" {
+			justification = "ignored by rule " + ignored.Rule
+		}
+		findings = append(findings, finding{
+			err:           ignored.Error,
+			suppression:   &sarifSuppression{Kind: "external", Justification: justification},
+			baselineState: state(ignored.Baselined),
+		})
+	}
+	for _, e := range doc.Baselined {
+		findings = append(findings, finding{
+			err:           e,
+			suppression:   &sarifSuppression{Kind: "external", Justification: "known error recorded in baseline"},
+			baselineState: state(true),
+		})
+	}
+
+	ruleIDs := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, f := range findings {
+		if _, ok := seen[f.err.Type]; ok {
+			continue
+		}
+		seen[f.err.Type] = struct{}{}
+		ruleIDs = append(ruleIDs, f.err.Type)
+	}
+	sort.Strings(ruleIDs)
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	ruleIndex := make(map[string]int, len(ruleIDs))
+	for i, id := range ruleIDs {
+		info := sarifRuleFor(id)
+		ruleIndex[id] = i
+		rules = append(rules, sarifRule{
+			ID:                   id,
+			Name:                 info.name,
+			ShortDescription:     sarifMessage{Text: info.description},
+			DefaultConfiguration: sarifConfiguration{Level: info.level},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		e := f.err
+		result := sarifResult{
+			RuleID:    e.Type,
+			RuleIndex: ruleIndex[e.Type],
+			Level:     sarifLevel(e),
+			Message:   sarifMessage{Text: sarifResultMessage(e)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: opts.artifactLocation(e.Source)},
+			}},
+			PartialFingerprints: map[string]string{"linkcheck/v1": sarifFingerprint(e)},
+			BaselineState:       f.baselineState,
+			Properties:          newSARIFProperties(e),
+		}
+		if f.suppression != nil {
+			result.Suppressions = []sarifSuppression{*f.suppression}
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "linkcheck", Rules: rules}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func sarifRuleFor(id string) sarifRuleInfo {
+	if info, ok := sarifRules[id]; ok {
+		return info
+	}
+	return sarifRuleInfo{name: id, description: fmt.Sprintf("Link check failed (%s).", id), level: "warning"}
+}
+
+// sarifLevel maps the error's severity to a SARIF level, falling back to the
+// rule default for unclassified errors.
+func sarifLevel(e Error) string {
+	switch crawler.Severity(e.Severity) {
+	case crawler.SeverityError:
+		return "error"
+	case crawler.SeverityWarning:
+		return "warning"
+	case crawler.SeverityInfo:
+		return "note"
+	default:
+		return sarifRuleFor(e.Type).level
+	}
+}
+
+func sarifResultMessage(e Error) string {
+	if e.Source == e.Target {
+		return fmt.Sprintf("Page %s failed: %s", e.Target, e.Message)
+	}
+	if e.Link != nil && e.Link.Text != "" {
+		return fmt.Sprintf("Link %q to %s failed: %s", e.Link.Text, e.Target, e.Message)
+	}
+	return fmt.Sprintf("Link to %s failed: %s", e.Target, e.Message)
+}
+
+func newSARIFProperties(e Error) sarifProperties {
+	props := sarifProperties{Source: e.Source, Target: e.Target, Status: e.Status}
+	if e.Link != nil {
+		props.LinkText = e.Link.Text
+		props.LinkRel = e.Link.Rel
+	}
+	return props
+}
+
+func sarifFingerprint(e Error) string {
+	sum := sha256.Sum256([]byte(e.Type + "\x00" + e.Source + "\x00" + e.Target))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (opts SARIFOptions) artifactLocation(pageURL string) sarifArtifactLocation {
+	for _, mapping := range opts.PathMappings {
+		if !mapping.matches(pageURL) {
+			continue
+		}
+		return sarifArtifactLocation{URI: mapping.rewrite(pageURL), URIBaseID: sarifSrcRoot}
+	}
+	return sarifArtifactLocation{URI: pageURL}
+}
+
+// matches reports whether pageURL lies under URLPrefix. The prefix must end
+// on a path segment boundary, so "/docs" covers "/docs/a" but not "/docs-old".
+func (m SARIFPathMapping) matches(pageURL string) bool {
+	if m.URLPrefix == "" || !strings.HasPrefix(pageURL, m.URLPrefix) {
+		return false
+	}
+	rest := pageURL[len(m.URLPrefix):]
+	return rest == "" || strings.HasSuffix(m.URLPrefix, "/") || strings.ContainsAny(rest[:1], "/?#")
+}
+
+func (m SARIFPathMapping) rewrite(pageURL string) string {
+	rest := strings.TrimPrefix(pageURL, m.URLPrefix)
+	if parsed, err := url.Parse(rest); err == nil {
+		rest = parsed.Path
+	}
+	if rest == "" || strings.HasSuffix(rest, "/") {
+		index := m.IndexName
+		if index == "" {
+			index = "index"
+		}
+		ext := m.Extension
+		if ext == "" {
+			ext = ".html"
+		}
+		rest += index + ext
+	} else if m.Extension != "" {
+		rest = strings.TrimSuffix(rest, path.Ext(rest)) + m.Extension
+	}
+	prefix := strings.TrimSuffix(m.PathPrefix, "/")
+	rest = strings.TrimPrefix(rest, "/")
+	if prefix == "" {
+		return rest
+	}
+	return prefix + "/" + rest
+}